@@ -0,0 +1,51 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// deriveAvailability appends a has_<fuel> column for every fuel price column,
+// set to true when the site publishes a price for that fuel and false
+// otherwise. The column names have no dots, so they become top-level keys in
+// the nested formats.
+func deriveAvailability(payload []byte) ([]byte, error) {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var prices []int
+	extended := slices.Clone(header)
+	for i, key := range header {
+		code, ok := strings.CutPrefix(key, fuel.PricePrefix)
+		if !ok {
+			continue
+		}
+		name := "has_" + strings.ToLower(code)
+		if slices.Contains(header, name) {
+			return nil, fmt.Errorf("column %s already exists", name)
+		}
+		prices = append(prices, i)
+		extended = append(extended, name)
+	}
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("no %s* columns", fuel.PricePrefix)
+	}
+
+	for i, row := range rows {
+		for _, index := range prices {
+			row = append(row, strconv.FormatBool(fieldAt(row, index) != ""))
+		}
+		rows[i] = row
+	}
+	return fuel.WriteCSV(extended, rows)
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// badRow is a row dropped by -skip-bad-rows. line is the 1-based line of the
+// source on which the record starts, counting the header.
+type badRow struct {
+	line     int
+	fields   int
+	expected int
+}
+
+func (r badRow) String() string {
+	return fmt.Sprintf("line %d: %d fields, expected %d", r.line, r.fields, r.expected)
+}
+
+// skipBadRows drops rows whose field count differs from the header, which
+// the converters would otherwise reject for the whole file. It returns the
+// re-encoded CSV, the number of rows kept and the rows dropped.
+func skipBadRows(payload []byte) ([]byte, int, []badRow, error) {
+	reader := fuel.NewCSVReader(payload)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	var rows [][]string
+	var bad []badRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		if fuel.BlankRecord(record) {
+			continue
+		}
+		if len(record) != len(header) {
+			line, _ := reader.FieldPos(0)
+			bad = append(bad, badRow{line: line, fields: len(record), expected: len(header)})
+			continue
+		}
+		rows = append(rows, record)
+	}
+	if len(bad) == 0 {
+		return payload, len(rows), nil, nil
+	}
+
+	output, err := fuel.WriteCSV(header, rows)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return output, len(rows), bad, nil
+}
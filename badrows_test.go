@@ -0,0 +1,48 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import "testing"
+
+func TestSkipBadRowsIgnoresBlankRows(t *testing.T) {
+	payload := []byte("forecourts.node_id,forecourts.fuel_price.E10\r\n" +
+		"abc123,129.9\r\n" +
+		"def456,131.9\r\n" +
+		",\r\n" +
+		" , \r\n" +
+		"\r\n")
+
+	output, rows, bad, err := skipBadRows(payload)
+	if err != nil {
+		t.Fatalf("skipBadRows: %v", err)
+	}
+	if rows != 2 || len(bad) != 0 {
+		t.Errorf("rows = %d, bad = %v, want 2 rows and none bad", rows, bad)
+	}
+	if string(output) != string(payload) {
+		t.Errorf("output was rewritten without bad rows: %q", output)
+	}
+}
+
+func TestSkipBadRowsDropsShortRows(t *testing.T) {
+	payload := []byte("forecourts.node_id,forecourts.fuel_price.E10\r\n" +
+		"abc123,129.9\r\n" +
+		"def456\r\n" +
+		",,\r\n")
+
+	output, rows, bad, err := skipBadRows(payload)
+	if err != nil {
+		t.Fatalf("skipBadRows: %v", err)
+	}
+	if rows != 1 || len(bad) != 1 {
+		t.Fatalf("rows = %d, bad = %v, want 1 row and 1 bad", rows, bad)
+	}
+	if bad[0].line != 3 || bad[0].fields != 1 || bad[0].expected != 2 {
+		t.Errorf("bad row = %+v, want line 3 with 1 of 2 fields", bad[0])
+	}
+	if want := "forecourts.node_id,forecourts.fuel_price.E10\nabc123,129.9\n"; string(output) != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// loadCacheMeta reads the validators stored by the last successful run so
+// the next one can issue a conditional GET.
+func loadCacheMeta(path string) (fuel.CacheMeta, error) {
+	var meta fuel.CacheMeta
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return meta, nil
+	}
+	if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, err
+	}
+	return meta, nil
+}
+
+func saveCacheMeta(path string, meta fuel.CacheMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// loadFetchMarker returns the time of the last fetch attempt recorded at
+// path, or the zero time if there is none.
+func loadFetchMarker(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+}
+
+// saveFetchMarker records at as the time of the last fetch attempt.
+func saveFetchMarker(path string, at time.Time) error {
+	return writeFileAtomic(path, []byte(at.UTC().Format(time.RFC3339Nano)+"\n"), 0o644)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
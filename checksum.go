@@ -0,0 +1,53 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func newChecksumHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "md5":
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
+	}
+}
+
+// writeChecksum writes "<digest>  <name>" to path.<algorithm>, the format
+// understood by sha256sum -c and friends.
+func writeChecksum(path, algorithm string, sum []byte) error {
+	line := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum), filepath.Base(path))
+	return writeFileAtomic(path+"."+algorithm, []byte(line), 0o644)
+}
+
+func hashFile(path, algorithm string) ([]byte, error) {
+	h, err := newChecksumHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := io.Copy(h, file); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// selectColumns keeps only the named columns, in the order given.
+func selectColumns(payload []byte, columns []string) ([]byte, error) {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make([]int, len(columns))
+	for i, column := range columns {
+		index := slices.Index(header, column)
+		if index < 0 {
+			return nil, fmt.Errorf("missing column %s", column)
+		}
+		indexes[i] = index
+	}
+
+	projected := make([][]string, len(rows))
+	for i, row := range rows {
+		projected[i] = make([]string, len(indexes))
+		for j, index := range indexes {
+			projected[i][j] = fieldAt(row, index)
+		}
+	}
+	return fuel.WriteCSV(columns, projected)
+}
+
+// appendColumn adds a column named name holding value in every row.
+func appendColumn(payload []byte, name, value string) ([]byte, error) {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(header, name) {
+		return nil, fmt.Errorf("column %s already exists", name)
+	}
+	for i, row := range rows {
+		rows[i] = append(row, value)
+	}
+	return fuel.WriteCSV(append(header, name), rows)
+}
+
+// columnSet turns a comma-separated list of columns into a set.
+func columnSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, column := range splitList(list) {
+		set[column] = true
+	}
+	return set
+}
+
+// checkColumnsPresent fails if any column of set is missing from header.
+func checkColumnsPresent(header []string, set map[string]bool) error {
+	for column := range set {
+		if !slices.Contains(header, column) {
+			return fmt.Errorf("missing column %s", column)
+		}
+	}
+	return nil
+}
+
+// parseColumnTypes turns comma-separated column lists keyed by type into a
+// map from column to type, failing if a column is given two types.
+func parseColumnTypes(lists map[string]string) (map[string]string, error) {
+	types := make(map[string]string)
+	for _, kind := range []string{fuel.TypeNumeric, fuel.TypeString, fuel.TypeBool} {
+		for _, column := range splitList(lists[kind]) {
+			if previous, ok := types[column]; ok && previous != kind {
+				return nil, fmt.Errorf("column %s cannot be both -%s and -%s", column, previous, kind)
+			}
+			types[column] = kind
+		}
+	}
+	return types, nil
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyConfigFile sets every flag named in the YAML file at path that was
+// not given on the command line. Keys are flag names without the leading
+// dash; repeatable flags take a list. Scalars are passed to the flag as
+// written, so "0640" stays an octal -mode and "15m" a duration. Flags left
+// out of the file keep their environment or built-in defaults.
+func applyConfigFile(flags *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var values map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if name == "config" || flags.Lookup(name) == nil {
+			return fmt.Errorf("unknown option %s", name)
+		}
+		if explicit[name] {
+			continue
+		}
+		node := values[name]
+		var items []*yaml.Node
+		switch node.Kind {
+		case yaml.ScalarNode:
+			items = []*yaml.Node{&node}
+		case yaml.SequenceNode:
+			items = node.Content
+		default:
+			return fmt.Errorf("%s: expected a value or a list", name)
+		}
+		for _, item := range items {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("%s: expected a value", name)
+			}
+			if err := flags.Set(name, item.Value); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// defaultCoordBounds covers Great Britain and Northern Ireland, with the
+// Channel Islands and Shetland.
+const defaultCoordBounds = "49.8,-8.7,60.9,1.8"
+
+// coordBounds is the box of plausible forecourt locations used by
+// -fix-coord-swap.
+type coordBounds struct {
+	minLat, minLon float64
+	maxLat, maxLon float64
+}
+
+func (b coordBounds) contains(lat, lon float64) bool {
+	return lat >= b.minLat && lat <= b.maxLat && lon >= b.minLon && lon <= b.maxLon
+}
+
+// parseCoordBounds parses a "minlat,minlon,maxlat,maxlon" box.
+func parseCoordBounds(value string) (coordBounds, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return coordBounds{}, fmt.Errorf("expected minlat,minlon,maxlat,maxlon but got %q", value)
+	}
+	var numbers [4]float64
+	for i, part := range parts {
+		number, err := parseFloat(strings.TrimSpace(part))
+		if err != nil {
+			return coordBounds{}, fmt.Errorf("parse %q: %w", part, err)
+		}
+		numbers[i] = number
+	}
+	bounds := coordBounds{minLat: numbers[0], minLon: numbers[1], maxLat: numbers[2], maxLon: numbers[3]}
+	if bounds.minLat > bounds.maxLat || bounds.minLon > bounds.maxLon {
+		return coordBounds{}, fmt.Errorf("minimum above maximum in %q", value)
+	}
+	return bounds, nil
+}
+
+// coordSwap records a row whose latitude and longitude were exchanged. row
+// is the 1-based data row, not counting the header.
+type coordSwap struct {
+	row      int
+	siteID   string
+	lat, lon float64
+}
+
+func (s coordSwap) String() string {
+	return fmt.Sprintf("row %d (%s): latitude %v and longitude %v", s.row, s.siteID, s.lat, s.lon)
+}
+
+// fixCoordSwaps exchanges the latitude and longitude of rows that fall
+// outside bounds but would fall inside once swapped. Rows with missing or
+// unparseable coordinates are left alone. It returns the re-encoded CSV and
+// the swaps made, with the coordinates as they were in the feed.
+func fixCoordSwaps(payload []byte, bounds coordBounds) ([]byte, []coordSwap, error) {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	latIndex, lonIndex, siteIndex := -1, -1, -1
+	for i, column := range header {
+		switch column {
+		case latitudeColumn:
+			latIndex = i
+		case longitudeColumn:
+			lonIndex = i
+		case siteIDColumn:
+			siteIndex = i
+		}
+	}
+	if latIndex < 0 || lonIndex < 0 {
+		return nil, nil, fmt.Errorf("missing column %s or %s", latitudeColumn, longitudeColumn)
+	}
+
+	var swaps []coordSwap
+	for i, row := range rows {
+		if len(row) != len(header) {
+			continue
+		}
+		lat, err := parseFloat(strings.TrimSpace(row[latIndex]))
+		if err != nil {
+			continue
+		}
+		lon, err := parseFloat(strings.TrimSpace(row[lonIndex]))
+		if err != nil {
+			continue
+		}
+		if bounds.contains(lat, lon) || !bounds.contains(lon, lat) {
+			continue
+		}
+		row[latIndex], row[lonIndex] = row[lonIndex], row[latIndex]
+		swap := coordSwap{row: i + 1, lat: lat, lon: lon}
+		if siteIndex >= 0 {
+			swap.siteID = row[siteIndex]
+		}
+		swaps = append(swaps, swap)
+	}
+	if len(swaps) == 0 {
+		return payload, nil, nil
+	}
+
+	output, err := fuel.WriteCSV(header, rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	return output, swaps, nil
+}
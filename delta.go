@@ -0,0 +1,96 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// deltaSuffix is appended to a fuel price column to name its delta column.
+// The delta keeps the price prefix, so it is typed as a nullable number and
+// nests next to the price in JSON.
+const deltaSuffix = "_delta"
+
+// addPriceDeltas appends a <price>_delta column for every fuel price column
+// of current, holding the change since the previous snapshot for the same
+// site. The delta is empty (null in typed output) when the site is new or
+// either price is missing.
+func addPriceDeltas(previous, current []byte) ([]byte, error) {
+	prevHeader, prevRows, err := fuel.ReadCSV(previous)
+	if err != nil {
+		return nil, fmt.Errorf("read previous: %w", err)
+	}
+	header, rows, err := fuel.ReadCSV(current)
+	if err != nil {
+		return nil, fmt.Errorf("read current: %w", err)
+	}
+
+	prevID := slices.Index(prevHeader, siteIDColumn)
+	curID := slices.Index(header, siteIDColumn)
+	if prevID < 0 || curID < 0 {
+		return nil, fmt.Errorf("missing column %s", siteIDColumn)
+	}
+
+	type priceColumn struct {
+		previous int
+		current  int
+	}
+	var prices []priceColumn
+	extended := slices.Clone(header)
+	for i, column := range header {
+		if !strings.HasPrefix(column, fuel.PricePrefix) || strings.HasSuffix(column, deltaSuffix) {
+			continue
+		}
+		name := column + deltaSuffix
+		if slices.Contains(header, name) {
+			return nil, fmt.Errorf("column %s already exists", name)
+		}
+		prices = append(prices, priceColumn{previous: slices.Index(prevHeader, column), current: i})
+		extended = append(extended, name)
+	}
+
+	previousSites := make(map[string][]string, len(prevRows))
+	for _, row := range prevRows {
+		previousSites[fieldAt(row, prevID)] = row
+	}
+
+	for i, row := range rows {
+		old, ok := previousSites[fieldAt(row, curID)]
+		for _, price := range prices {
+			delta := ""
+			if ok && price.previous >= 0 {
+				delta = priceDelta(fieldAt(old, price.previous), fieldAt(row, price.current))
+			}
+			row = append(row, delta)
+		}
+		rows[i] = row
+	}
+	return fuel.WriteCSV(extended, rows)
+}
+
+// priceDelta returns after minus before, rounded to the four decimal places
+// prices are published with, or an empty string if either is not a number.
+func priceDelta(before, after string) string {
+	left, err := parseFloat(strings.TrimSpace(before))
+	if err != nil {
+		return ""
+	}
+	right, err := parseFloat(strings.TrimSpace(after))
+	if err != nil {
+		return ""
+	}
+	delta := math.Round((right-left)*1e4) / 1e4
+	if delta == 0 {
+		// Avoid writing -0.
+		delta = 0
+	}
+	return strconv.FormatFloat(delta, 'f', -1, 64)
+}
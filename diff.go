@@ -0,0 +1,116 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// printDiff compares two CSV snapshots keyed by site id and writes the sites
+// that were added, removed or had any fuel price change.
+func printDiff(w io.Writer, previous, current []byte) error {
+	prevHeader, prevRows, err := fuel.ReadCSV(previous)
+	if err != nil {
+		return fmt.Errorf("read previous: %w", err)
+	}
+	curHeader, curRows, err := fuel.ReadCSV(current)
+	if err != nil {
+		return fmt.Errorf("read current: %w", err)
+	}
+
+	prevID := slices.Index(prevHeader, siteIDColumn)
+	curID := slices.Index(curHeader, siteIDColumn)
+	if prevID < 0 || curID < 0 {
+		return fmt.Errorf("missing column %s", siteIDColumn)
+	}
+	prevName := slices.Index(prevHeader, tradingNameColumn)
+	curName := slices.Index(curHeader, tradingNameColumn)
+
+	type priceColumn struct {
+		fuel     string
+		previous int
+		current  int
+	}
+	var prices []priceColumn
+	for i, column := range curHeader {
+		if !strings.HasPrefix(column, fuel.PricePrefix) {
+			continue
+		}
+		prices = append(prices, priceColumn{
+			fuel:     strings.TrimPrefix(column, fuel.PricePrefix),
+			previous: slices.Index(prevHeader, column),
+			current:  i,
+		})
+	}
+
+	previousSites := make(map[string][]string, len(prevRows))
+	for _, row := range prevRows {
+		previousSites[fieldAt(row, prevID)] = row
+	}
+	currentSites := make(map[string]struct{}, len(curRows))
+
+	var added, removed, changed int
+	for _, row := range curRows {
+		id := fieldAt(row, curID)
+		currentSites[id] = struct{}{}
+		old, ok := previousSites[id]
+		if !ok {
+			added++
+			fmt.Fprintf(w, "+ %s %s\n", id, fieldAt(row, curName))
+			continue
+		}
+
+		var changes []string
+		for _, price := range prices {
+			before := fieldAt(old, price.previous)
+			after := fieldAt(row, price.current)
+			if !samePrice(before, after) {
+				changes = append(changes, fmt.Sprintf("%s %s -> %s", price.fuel, displayPrice(before), displayPrice(after)))
+			}
+		}
+		if len(changes) > 0 {
+			changed++
+			fmt.Fprintf(w, "~ %s %s: %s\n", id, fieldAt(row, curName), strings.Join(changes, ", "))
+		}
+	}
+
+	for _, row := range prevRows {
+		id := fieldAt(row, prevID)
+		if _, ok := currentSites[id]; !ok {
+			removed++
+			fmt.Fprintf(w, "- %s %s\n", id, fieldAt(row, prevName))
+		}
+	}
+
+	fmt.Fprintf(w, "diff: %d added, %d removed, %d changed\n", added, removed, changed)
+	return nil
+}
+
+func samePrice(a, b string) bool {
+	if a == b {
+		return true
+	}
+	left, err := parseFloat(a)
+	if err != nil {
+		return false
+	}
+	right, err := parseFloat(b)
+	if err != nil {
+		return false
+	}
+	return left == right
+}
+
+func displayPrice(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+const (
+	siteIDColumn      = "forecourts.node_id"
+	tradingNameColumn = "forecourts.trading_name"
+	brandColumn       = "forecourts.brand_name"
+	postcodeColumn    = "forecourts.location.postcode"
+)
+
+// rowFilter keeps a row when keep returns true for the values of columns,
+// supplied in the same order as they are listed.
+type rowFilter struct {
+	columns []string
+	keep    func(values []string) bool
+}
+
+// filterRows drops every row that fails any of the filters, preserving the
+// header. It returns the re-encoded CSV and the number of rows kept.
+func filterRows(payload []byte, filters []rowFilter) ([]byte, int, error) {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	indexes := make([][]int, len(filters))
+	for i, filter := range filters {
+		for _, column := range filter.columns {
+			index := slices.Index(header, column)
+			if index < 0 {
+				return nil, 0, fmt.Errorf("missing column %s", column)
+			}
+			indexes[i] = append(indexes[i], index)
+		}
+	}
+
+	kept := rows[:0]
+	for _, row := range rows {
+		if matchesFilters(row, filters, indexes) {
+			kept = append(kept, row)
+		}
+	}
+
+	output, err := fuel.WriteCSV(header, kept)
+	if err != nil {
+		return nil, 0, err
+	}
+	return output, len(kept), nil
+}
+
+func matchesFilters(row []string, filters []rowFilter, indexes [][]int) bool {
+	for i, filter := range filters {
+		values := make([]string, len(indexes[i]))
+		for j, index := range indexes[i] {
+			values[j] = fieldAt(row, index)
+		}
+		if !filter.keep(values) {
+			return false
+		}
+	}
+	return true
+}
+
+func brandFilter(brands []string) rowFilter {
+	return rowFilter{
+		columns: []string{brandColumn},
+		keep: func(values []string) bool {
+			brand := strings.TrimSpace(values[0])
+			for _, candidate := range brands {
+				if strings.EqualFold(brand, candidate) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+func postcodeFilter(prefixes []string) rowFilter {
+	return rowFilter{
+		columns: []string{postcodeColumn},
+		keep: func(values []string) bool {
+			postcode := strings.ToUpper(strings.TrimSpace(values[0]))
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(postcode, strings.ToUpper(prefix)) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// dedupeFilter keeps the first row seen for each value of column and counts
+// the rows it rejects in dropped.
+func dedupeFilter(column string, dropped *int) rowFilter {
+	seen := make(map[string]struct{})
+	return rowFilter{
+		columns: []string{column},
+		keep: func(values []string) bool {
+			if _, ok := seen[values[0]]; ok {
+				*dropped++
+				return false
+			}
+			seen[values[0]] = struct{}{}
+			return true
+		},
+	}
+}
+
+// requiredFilter drops rows with an empty value in any of columns and
+// counts them in dropped.
+func requiredFilter(columns []string, dropped *int) rowFilter {
+	return rowFilter{
+		columns: columns,
+		keep: func(values []string) bool {
+			for _, value := range values {
+				if strings.TrimSpace(value) == "" {
+					*dropped++
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+// fuelFilter keeps rows with a price for every one of the fuel codes, such
+// as E10 or B7S.
+func fuelFilter(codes []string) rowFilter {
+	columns := make([]string, len(codes))
+	for i, code := range codes {
+		columns[i] = fuel.PricePrefix + strings.ToUpper(code)
+	}
+	return rowFilter{
+		columns: columns,
+		keep: func(values []string) bool {
+			for _, value := range values {
+				if strings.TrimSpace(value) == "" {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+const (
+	latitudeColumn  = "forecourts.location.latitude"
+	longitudeColumn = "forecourts.location.longitude"
+
+	earthRadiusKm = 6371.0
+)
+
+// parseCoordinates parses a "lat,long" pair such as "51.48,-3.18".
+func parseCoordinates(value string) (float64, float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected lat,long but got %q", value)
+	}
+	lat, err := parseFloat(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("parse latitude: %w", err)
+	}
+	lon, err := parseFloat(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("parse longitude: %w", err)
+	}
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("coordinates out of range: %q", value)
+	}
+	return lat, lon, nil
+}
+
+// haversineKm returns the great-circle distance between two points.
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+func toRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// nearFilter keeps forecourts within radiusKm of the given point. Rows with
+// missing or unparseable coordinates are dropped.
+func nearFilter(lat, lon, radiusKm float64) rowFilter {
+	return rowFilter{
+		columns: []string{latitudeColumn, longitudeColumn},
+		keep: func(values []string) bool {
+			siteLat, err := parseFloat(strings.TrimSpace(values[0]))
+			if err != nil {
+				return false
+			}
+			siteLon, err := parseFloat(strings.TrimSpace(values[1]))
+			if err != nil {
+				return false
+			}
+			return haversineKm(lat, lon, siteLat, siteLon) <= radiusKm
+		},
+	}
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// convertCSVToGeoJSON builds a FeatureCollection with one Point per
+// forecourt. Every column other than the coordinates is nested into the
+// feature properties the same way as the JSON output.
+func convertCSVToGeoJSON(payload []byte, opts convertOptions) ([]byte, error) {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(header) == 0 {
+		return nil, errors.New("missing header row")
+	}
+
+	latIndex := slices.Index(header, latitudeColumn)
+	lonIndex := slices.Index(header, longitudeColumn)
+	if latIndex < 0 || lonIndex < 0 {
+		return nil, errors.New("missing coordinate columns")
+	}
+
+	var propertyHeader []string
+	var propertyIndexes []int
+	for i, key := range header {
+		if i != latIndex && i != lonIndex {
+			propertyHeader = append(propertyHeader, key)
+			propertyIndexes = append(propertyIndexes, i)
+		}
+	}
+
+	collection := geoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: make([]geoJSONFeature, 0, len(rows)),
+	}
+	propertyRow := make([]string, len(propertyIndexes))
+	for line, row := range rows {
+		if len(row) != len(header) {
+			return nil, fmt.Errorf("row has %d fields, expected %d", len(row), len(header))
+		}
+		if row[latIndex] == "" || row[lonIndex] == "" {
+			return nil, fmt.Errorf("row %d: missing coordinates", line+1)
+		}
+		lat, err := geometryCoordinate(latitudeColumn, row[latIndex], opts.ConvertOptions)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: parse latitude: %w", line+1, err)
+		}
+		lon, err := geometryCoordinate(longitudeColumn, row[lonIndex], opts.ConvertOptions)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: parse longitude: %w", line+1, err)
+		}
+
+		for i, index := range propertyIndexes {
+			propertyRow[i] = row[index]
+		}
+		properties, err := fuel.BuildRecord(propertyHeader, propertyRow, opts.ConvertOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "Point", Coordinates: [2]float64{lon, lat}},
+			Properties: properties,
+		})
+	}
+
+	if opts.Indent == "" {
+		return json.Marshal(collection)
+	}
+	return json.MarshalIndent(collection, "", opts.Indent)
+}
+
+// geometryCoordinate parses a point coordinate through fuel.NormalizeValue,
+// so -round-coords applies to the geometry as it does to the properties.
+// A coordinate column declared a string with -string is used as published.
+func geometryCoordinate(key, raw string, opts fuel.ConvertOptions) (float64, error) {
+	value, err := fuel.NormalizeValue(key, raw, opts)
+	if err != nil {
+		return 0, err
+	}
+	switch number := value.(type) {
+	case float64:
+		return number, nil
+	case int64:
+		return float64(number), nil
+	}
+	return parseFloat(raw)
+}
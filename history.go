@@ -0,0 +1,200 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"fuelfinder-archive/pkg/fuel"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Suffixes of the columns added by -history-stats. Like the delta they keep
+// the price prefix, so they are typed as nullable numbers and nest next to
+// the price in JSON.
+const (
+	historyMinSuffix = "_min"
+	historyMaxSuffix = "_max"
+)
+
+// Buckets of the -history-db store. Observations holds a nested bucket per
+// site and fuel, keyed by observation time; summaries holds the running
+// priceSummary for the same key, so stats never need a full scan.
+var (
+	observationsBucket = []byte("observations")
+	summariesBucket    = []byte("summaries")
+)
+
+// priceSummary is the running summary of one site's price for one fuel.
+type priceSummary struct {
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Count int       `json:"count"`
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+}
+
+// historyKey identifies a site's price for one fuel, such as
+// "<node id>\x00E10".
+func historyKey(siteID, code string) []byte {
+	return []byte(siteID + "\x00" + code)
+}
+
+// recordHistory adds each published price in payload to the store at path
+// as an observation at time at. With stats set it appends <price>_min and
+// <price>_max columns holding the lowest and highest price seen for the
+// site, this run included. Unless commit is set the observations are rolled
+// back and a missing store is left uncreated, for -dry-run.
+func recordHistory(path string, payload []byte, at time.Time, stats, commit bool) ([]byte, error) {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return nil, err
+	}
+	siteIndex := slices.Index(header, siteIDColumn)
+	if siteIndex < 0 {
+		return nil, fmt.Errorf("missing column %s", siteIDColumn)
+	}
+
+	type priceColumn struct {
+		index int
+		code  string
+	}
+	var prices []priceColumn
+	extended := slices.Clone(header)
+	for i, column := range header {
+		code, ok := strings.CutPrefix(column, fuel.PricePrefix)
+		if !ok || strings.Contains(code, "_") {
+			continue
+		}
+		prices = append(prices, priceColumn{index: i, code: code})
+		if stats {
+			for _, name := range []string{column + historyMinSuffix, column + historyMaxSuffix} {
+				if slices.Contains(header, name) {
+					return nil, fmt.Errorf("column %s already exists", name)
+				}
+				extended = append(extended, name)
+			}
+		}
+	}
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("no %s* columns", fuel.PricePrefix)
+	}
+
+	var tx *bolt.Tx
+	if _, err := os.Stat(path); commit || !errors.Is(err, fs.ErrNotExist) {
+		db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: 5 * time.Second})
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", path, err)
+		}
+		defer db.Close()
+		tx, err = db.Begin(true)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+	}
+	store, err := newHistoryStore(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	observedAt := make([]byte, 8)
+	binary.BigEndian.PutUint64(observedAt, uint64(at.UnixNano()))
+	for i, row := range rows {
+		siteID := fieldAt(row, siteIndex)
+		for _, price := range prices {
+			raw := strings.TrimSpace(fieldAt(row, price.index))
+			value, err := parseFloat(raw)
+			if raw == "" || siteID == "" || err != nil {
+				if stats {
+					row = append(row, "", "")
+				}
+				continue
+			}
+			summary, err := store.observe(historyKey(siteID, price.code), observedAt, raw, value, at)
+			if err != nil {
+				return nil, fmt.Errorf("record %s %s: %w", siteID, price.code, err)
+			}
+			if stats {
+				row = append(row, strconv.FormatFloat(summary.Min, 'f', -1, 64), strconv.FormatFloat(summary.Max, 'f', -1, 64))
+			}
+		}
+		rows[i] = row
+	}
+
+	if commit {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("commit %s: %w", path, err)
+		}
+	}
+	if !stats {
+		return payload, nil
+	}
+	return fuel.WriteCSV(extended, rows)
+}
+
+// historyStore wraps the buckets of a writable transaction. A nil
+// transaction stands in for a store that does not exist yet.
+type historyStore struct {
+	observations *bolt.Bucket
+	summaries    *bolt.Bucket
+}
+
+func newHistoryStore(tx *bolt.Tx) (*historyStore, error) {
+	if tx == nil {
+		return &historyStore{}, nil
+	}
+	observations, err := tx.CreateBucketIfNotExists(observationsBucket)
+	if err != nil {
+		return nil, err
+	}
+	summaries, err := tx.CreateBucketIfNotExists(summariesBucket)
+	if err != nil {
+		return nil, err
+	}
+	return &historyStore{observations: observations, summaries: summaries}, nil
+}
+
+// observe stores raw as the price for key at observedAt and returns the
+// summary updated with value.
+func (s *historyStore) observe(key, observedAt []byte, raw string, value float64, at time.Time) (priceSummary, error) {
+	summary := priceSummary{Min: value, Max: value, First: at}
+	if s.summaries == nil {
+		summary.Count, summary.Last = 1, at
+		return summary, nil
+	}
+	if data := s.summaries.Get(key); data != nil {
+		if err := json.Unmarshal(data, &summary); err != nil {
+			return priceSummary{}, fmt.Errorf("decode summary: %w", err)
+		}
+		summary.Min = min(summary.Min, value)
+		summary.Max = max(summary.Max, value)
+	}
+	summary.Count++
+	summary.Last = at
+
+	observations, err := s.observations.CreateBucketIfNotExists(key)
+	if err != nil {
+		return priceSummary{}, err
+	}
+	if err := observations.Put(observedAt, []byte(raw)); err != nil {
+		return priceSummary{}, err
+	}
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return priceSummary{}, err
+	}
+	return summary, s.summaries.Put(key, data)
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// forecourtsSchema is the JSON Schema of the default -format json output.
+//
+//go:embed forecourts.schema.json
+var forecourtsSchema []byte
+
+// maxSchemaProblems limits how many violations -validate-json prints.
+const maxSchemaProblems = 20
+
+// jsonSchema is the subset of JSON Schema used by forecourts.schema.json:
+// types, object properties, array items and string length.
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	MaxLength            *int                   `json:"maxLength"`
+}
+
+// schemaTypes accepts "type" as either a single name or a list of names.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+// validateJSON checks a JSON document against the embedded schema and
+// returns one message per violation, each prefixed with the JSON pointer of
+// the offending value.
+func validateJSON(data []byte) ([]string, error) {
+	var schema jsonSchema
+	if err := json.Unmarshal(forecourtsSchema, &schema); err != nil {
+		return nil, fmt.Errorf("parse embedded schema: %w", err)
+	}
+
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	schema.check(document, "", &problems)
+	return problems, nil
+}
+
+func (s *jsonSchema) check(value any, pointer string, problems *[]string) {
+	kind := jsonKind(value)
+	if len(s.Type) > 0 && !slices.Contains(s.Type, kind) {
+		*problems = append(*problems, fmt.Sprintf("%s: expected %s but got %s", displayPointer(pointer), strings.Join(s.Type, " or "), kind))
+		return
+	}
+
+	switch value := value.(type) {
+	case string:
+		if s.MaxLength != nil && utf8.RuneCountInString(value) > *s.MaxLength {
+			*problems = append(*problems, fmt.Sprintf("%s: string %q longer than %d characters", displayPointer(pointer), value, *s.MaxLength))
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range value {
+				s.Items.check(item, fmt.Sprintf("%s/%d", pointer, i), problems)
+			}
+		}
+	case map[string]any:
+		for _, key := range s.Required {
+			if _, ok := value[key]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: missing property %s", displayPointer(pointer), key))
+			}
+		}
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			child := pointer + "/" + escapePointer(key)
+			if property, ok := s.Properties[key]; ok {
+				property.check(value[key], child, problems)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*problems = append(*problems, fmt.Sprintf("%s: unexpected property", displayPointer(child)))
+			}
+		}
+	}
+}
+
+// jsonKind names the JSON Schema type of a value decoded by encoding/json.
+func jsonKind(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+func displayPointer(pointer string) string {
+	if pointer == "" {
+		return "/"
+	}
+	return pointer
+}
@@ -0,0 +1,18 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile is not implemented on this platform, so -append refuses to run
+// rather than risk interleaved writes.
+func lockFile(file *os.File) error {
+	return errors.New("file locking is not supported on this platform")
+}
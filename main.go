@@ -6,227 +6,436 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
 	"encoding/csv"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
+	"unicode/utf8"
+
+	"fuelfinder-archive/pkg/fuel"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"gopkg.in/yaml.v3"
 )
 
-const fuelFinderURL = "https://www.fuel-finder.service.gov.uk/internal/v1.0.2/csv/get-latest-fuel-prices-csv"
+// Exit statuses, so monitoring can tell failure classes apart. Invalid
+// flags and other errors not classified below exit with exitUsage.
+const (
+	exitUsage      = 1
+	exitFetch      = 2
+	exitValidation = 3
+	exitWrite      = 4
+	// exitDeadline matches timeout(1) for a run cut short by -deadline.
+	exitDeadline = 124
+	// exitInterrupted follows the shell convention of 128 plus the
+	// signal number for SIGINT.
+	exitInterrupted = 130
+)
 
-func main() {
-	outPath := flag.String("out", getEnvDefault("FUEL_OUT", "data.csv"), "output path for CSV data")
-	outputPath := flag.String("output", "", "output path for CSV data")
-	format := flag.String("format", getEnvDefault("FUEL_FORMAT", "csv"), "output format: csv or json")
-	flag.Parse()
+// exitError records the exit status for an error.
+type exitError struct {
+	code int
+	err  error
+}
 
-	if *outputPath != "" {
-		*outPath = *outputPath
-	}
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
 
-	if *format == "json" && *outPath == "data.csv" {
-		*outPath = "data.json"
+// withExitCode classifies err unless it already carries an exit status, so
+// the innermost classification wins.
+func withExitCode(code int, err error) error {
+	var classified *exitError
+	if errors.As(err, &classified) {
+		return err
 	}
+	return &exitError{code: code, err: err}
+}
+
+func fetchError(err error) error      { return withExitCode(exitFetch, err) }
+func validationError(err error) error { return withExitCode(exitValidation, err) }
+func writeError(err error) error      { return withExitCode(exitWrite, err) }
 
-	if *outPath == "" {
-		exitWithError(errors.New("output path cannot be empty"))
+func main() {
+	opts := newOptions(flag.CommandLine)
+	// Parse errors exit with exitUsage rather than the flag package's 2,
+	// which is reserved for fetch failures.
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		os.Exit(exitUsage)
+	}
+	if err := opts.applyDefaults(flag.CommandLine); err != nil {
+		exitWithError(err)
 	}
 
-	if *format != "csv" && *format != "json" {
-		exitWithError(fmt.Errorf("unsupported format: %s", *format))
+	if opts.showVersion {
+		printVersion(os.Stdout)
+		return
 	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	payload, err := fetchFuelData(client)
+	stderr, err := setupLogging(opts)
 	if err != nil {
 		exitWithError(err)
 	}
 
-	if err := validateCSV(payload); err != nil {
-		exitWithError(fmt.Errorf("invalid CSV: %w", err))
+	if opts.validateJSONPath != "" {
+		if err := checkJSONFile(stderr, opts.validateJSONPath); err != nil {
+			exitWithError(err)
+		}
+		return
 	}
 
-	if *format == "json" {
-		jsonPayload, err := convertCSVToJSON(payload)
-		if err != nil {
-			exitWithError(fmt.Errorf("convert to JSON: %w", err))
+	if err := opts.validate(); err != nil {
+		exitWithError(err)
+	}
+	if opts.insecure {
+		fmt.Fprintln(stderr, "WARNING: -insecure disables TLS certificate verification; never use it against production endpoints")
+	}
+
+	// Cancel in-flight requests on SIGINT or SIGTERM. Output is only
+	// started while the context is still live and is then finished, and
+	// files are renamed into place, so an interrupted run never leaves a
+	// partial file behind.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// -deadline bounds everything below, including retries, waits and
+	// every run of -interval. The same rules as for a signal keep the
+	// output intact when it expires.
+	if opts.deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.deadline)
+		defer cancel()
+	}
+	exitIfDeadline := func() {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			exitWithError(&exitError{code: exitDeadline, err: fmt.Errorf("deadline of %v exceeded", opts.deadline)})
 		}
-		if err := os.WriteFile(*outPath, jsonPayload, 0o644); err != nil {
-			exitWithError(fmt.Errorf("write output: %w", err))
+	}
+
+	// A dry run writes nothing, so it must not leave directories behind
+	// either.
+	if opts.mkdir && !opts.dryRun {
+		if err := makeParentDirs(opts.outPath, opts.cacheMetaPath, opts.keepRaw, opts.manifestPath, opts.metricsPath, opts.fetchMarker, opts.historyDB); err != nil {
+			exitWithError(writeError(fmt.Errorf("create output directory: %w", err)))
+		}
+	}
+
+	r := newRunner(opts, stderr)
+	if opts.interval <= 0 {
+		if err := r.run(ctx); err != nil {
+			exitIfDeadline()
+			exitWithError(err)
 		}
 		return
 	}
 
-	if err := os.WriteFile(*outPath, payload, 0o644); err != nil {
-		exitWithError(fmt.Errorf("write output: %w", err))
+	if r.server != nil {
+		// Listen before the first fetch so a bad address fails at once.
+		listener, err := net.Listen("tcp", opts.serveAddr)
+		if err != nil {
+			exitWithError(fmt.Errorf("serve: %w", err))
+		}
+		httpServer := &http.Server{Handler: r.server.handler(), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("serve failed", "error", err)
+				stop()
+			}
+		}()
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			httpServer.Shutdown(shutdownCtx)
+		}()
+		fmt.Fprintf(stderr, "serving on %s\n", listener.Addr())
 	}
-}
 
-func validateCSV(payload []byte) error {
-	reader := csv.NewReader(bytes.NewReader(payload))
-	reader.FieldsPerRecord = -1
+	// Daemon mode: run until a signal arrives. A failed run is logged and
+	// retried at the next tick rather than ending the loop.
 	for {
-		_, err := reader.Read()
-		if err == nil {
-			continue
+		if err := r.run(ctx); err != nil {
+			if ctx.Err() != nil {
+				exitIfDeadline()
+				return
+			}
+			slog.Error("run failed", "error", err)
+			if r.server != nil {
+				r.server.fail()
+			}
 		}
-		if errors.Is(err, io.EOF) {
-			return nil
+		slog.Info("waiting for next run", "interval", opts.interval)
+		select {
+		case <-ctx.Done():
+			exitIfDeadline()
+			return
+		case <-time.After(opts.interval):
 		}
-		return err
 	}
 }
 
-func fetchFuelData(client *http.Client) ([]byte, error) {
-	var lastErr error
-	for _, target := range buildFuelFinderTargets() {
-		payload, err := fetchFuelDataFromURL(client, target)
-		if err != nil {
-			lastErr = err
-			continue
-		}
-		if len(payload) == 0 {
-			lastErr = errors.New("received empty response")
-			continue
-		}
-		return payload, nil
+// setupLogging installs the default logger at the -log-level and returns
+// the writer for diagnostics, which -quiet discards.
+func setupLogging(o *options) (io.Writer, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(o.logLevel)); err != nil {
+		return nil, fmt.Errorf("invalid -log-level: %w", err)
+	}
+	if o.verbose {
+		level = slog.LevelDebug
+	}
+	var stderr io.Writer = os.Stderr
+	if o.quiet {
+		stderr = io.Discard
 	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level})))
+	return stderr, nil
+}
 
-	if lastErr != nil {
-		return nil, lastErr
+// checkJSONFile validates the JSON output at path against the bundled
+// schema for -validate-json, listing the problems on stderr.
+func checkJSONFile(stderr io.Writer, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read JSON: %w", err)
+	}
+	problems, err := validateJSON(data)
+	if err != nil {
+		return fmt.Errorf("validate JSON: %w", err)
 	}
-	return nil, errors.New("failed to fetch fuel data")
+	for i, problem := range problems {
+		if i == maxSchemaProblems {
+			fmt.Fprintf(stderr, "... and %d more\n", len(problems)-i)
+			break
+		}
+		fmt.Fprintln(stderr, problem)
+	}
+	if len(problems) > 0 {
+		return validationError(fmt.Errorf("%s does not match the schema: %d problems", path, len(problems)))
+	}
+	fmt.Fprintf(stderr, "%s matches the schema\n", path)
+	return nil
 }
 
-func buildFuelFinderTargets() []string {
-	proxyTemplate := strings.TrimSpace(os.Getenv("FUEL_PROXY_TEMPLATE"))
-	if proxyTemplate == "" {
-		return []string{fuelFinderURL}
+func recordManifest(path string, manifest runManifest) error {
+	manifest.Version = version
+	manifest.Commit = commit
+	if err := writeManifest(path, manifest); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
 	}
+	return nil
+}
 
-	proxyURL := buildProxyURL(proxyTemplate, fuelFinderURL)
-	return []string{fuelFinderURL, proxyURL}
+func recordMetrics(path string, m runMetrics) error {
+	m.finished = time.Now()
+	if err := writeMetrics(path, m); err != nil {
+		return fmt.Errorf("write metrics: %w", err)
+	}
+	return nil
 }
 
-func buildProxyURL(template, target string) string {
-	if strings.Contains(template, "{url}") {
-		return strings.ReplaceAll(template, "{url}", url.QueryEscape(target))
+func saveFetchCache(path string, meta fuel.CacheMeta) error {
+	if path == "" || meta.Empty() {
+		return nil
 	}
-	return template + target
+	if err := saveCacheMeta(path, meta); err != nil {
+		return fmt.Errorf("write cache metadata: %w", err)
+	}
+	return nil
 }
 
-func fetchFuelDataFromURL(client *http.Client, target string) ([]byte, error) {
-	req, err := http.NewRequest(http.MethodGet, target, nil)
+var utf8BOM = []byte("\ufeff")
+
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// readInput reads the -input file, decompressing it when it is gzipped,
+// either by its .gz extension or by its content.
+func readInput(path string) ([]byte, error) {
+	payload, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, err
 	}
+	if !strings.HasSuffix(path, ".gz") && !bytes.HasPrefix(payload, gzipMagic) {
+		return payload, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("decompress %s: %w", path, err)
+	}
+	defer reader.Close()
+	payload, err = io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("decompress %s: %w", path, err)
+	}
+	return payload, nil
+}
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	req.Header.Set("Accept", "text/csv,application/octet-stream;q=0.9,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "en-GB,en;q=0.9")
-	req.Header.Set("Referer", "https://www.gov.uk/guidance/access-fuel-price-data")
-	req.Header.Set("Cache-Control", "no-cache")
-	req.Header.Set("Pragma", "no-cache")
-
-	resp, err := client.Do(req)
+// decodeCharset transcodes payload from the named character set to UTF-8.
+func decodeCharset(payload []byte, name string) ([]byte, error) {
+	encoding, err := htmlindex.Get(name)
 	if err != nil {
-		return nil, fmt.Errorf("fetch fuel data: %w", err)
+		return nil, fmt.Errorf("unknown charset %q", name)
 	}
-	defer resp.Body.Close()
+	return encoding.NewDecoder().Bytes(bytes.TrimPrefix(payload, utf8BOM))
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+// newHTTPClient returns a client that honours HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY, or always uses proxy when one is given. A non-nil tlsConfig
+// replaces the default TLS settings.
+func newHTTPClient(timeout time.Duration, proxy *url.URL, tlsConfig *tls.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
 	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
 
-	payload, err := io.ReadAll(resp.Body)
+// splitURLCredentials removes any user:pass from raw so it is never logged
+// or passed to a proxy template, returning it separately.
+func splitURLCredentials(raw string) (string, *url.Userinfo, error) {
+	target, err := url.Parse(raw)
 	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
+		return "", nil, err
 	}
+	credentials := target.User
+	if credentials == nil {
+		return raw, nil, nil
+	}
+	target.User = nil
+	return target.String(), credentials, nil
+}
 
-	return payload, nil
+func parseProxyURL(raw string) (*url.URL, error) {
+	proxy, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxy.Scheme)
+	}
+	if proxy.Host == "" {
+		return nil, errors.New("proxy host cannot be empty")
+	}
+	return proxy, nil
 }
 
-func convertCSVToJSON(payload []byte) ([]byte, error) {
-	reader := csv.NewReader(bytes.NewReader(payload))
-	reader.FieldsPerRecord = -1
+// underscoreColumns replaces the dots in header names with underscores for
+// formats that do not allow them, failing if two columns collide.
+func underscoreColumns(header []string) ([]string, error) {
+	names := make([]string, len(header))
+	seen := make(map[string]string, len(header))
+	for i, key := range header {
+		name := strings.ReplaceAll(key, ".", "_")
+		if previous, ok := seen[name]; ok {
+			return nil, fmt.Errorf("columns %s and %s both map to %s", previous, key, name)
+		}
+		seen[name] = key
+		names[i] = name
+	}
+	return names, nil
+}
 
-	header, err := reader.Read()
+// convertCSVDelimiter re-encodes the payload with comma as the field
+// separator, quoting any field that contains it, and renames the header.
+func convertCSVDelimiter(payload []byte, comma rune, opts convertOptions) ([]byte, error) {
+	header, rows, err := fuel.ReadCSV(payload)
 	if err != nil {
 		return nil, err
 	}
-	if len(header) == 0 {
-		return nil, errors.New("missing header row")
-	}
 
-	var records []map[string]any
-	for {
-		row, err := reader.Read()
-		if err == nil {
-			if len(row) != len(header) {
-				return nil, fmt.Errorf("row has %d fields, expected %d", len(row), len(header))
+	if opts.nullToken != "" {
+		for i, key := range header {
+			if kind := opts.ColumnType(key); kind != fuel.TypeNumeric && kind != fuel.TypeBool && !opts.Nulls[key] {
+				continue
 			}
-			entry := make(map[string]any, len(header))
-			for i, key := range header {
-				value, err := normalizeValue(key, row[i])
-				if err != nil {
-					return nil, fmt.Errorf("parse %s: %w", key, err)
-				}
-				if err := setNestedValue(entry, strings.Split(key, "."), value); err != nil {
-					return nil, fmt.Errorf("set %s: %w", key, err)
+			for _, row := range rows {
+				if i < len(row) && row[i] == "" {
+					row[i] = opts.nullToken
 				}
 			}
-			records = append(records, entry)
-			continue
 		}
-		if errors.Is(err, io.EOF) {
-			break
-		}
-		return nil, err
 	}
 
-	return json.MarshalIndent(records, "", "  ")
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Comma = comma
+	names := opts.OutputNames(header)
+	if opts.flattenSep != "" {
+		names = flattenNames(names, opts.flattenSep)
+	}
+	if err := writer.Write(names); err != nil {
+		return nil, err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
-func normalizeValue(key, raw string) (any, error) {
-	if raw == "" {
-		if isNullableNumericField(key) {
-			return nil, nil
-		}
-		return "", nil
+// parseDelimiter returns the single rune in value, rejecting characters
+// that cannot separate CSV fields.
+func parseDelimiter(value string) (rune, error) {
+	if utf8.RuneCountInString(value) != 1 {
+		return 0, fmt.Errorf("expected exactly one character but got %q", value)
 	}
-
-	if isNullableNumericField(key) {
-		value, err := parseFloat(raw)
-		if err != nil {
-			return nil, err
-		}
-		return value, nil
+	r, _ := utf8.DecodeRuneInString(value)
+	if r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, fmt.Errorf("%q cannot be used as a delimiter", r)
 	}
+	return r, nil
+}
 
-	if raw == "true" || raw == "false" {
-		value, err := parseBool(raw)
-		if err != nil {
-			return nil, err
-		}
-		return value, nil
+func convertCSVToYAML(payload []byte, opts convertOptions) ([]byte, error) {
+	records, err := fuel.ParseRecords(payload, opts.ConvertOptions)
+	if err != nil {
+		return nil, err
 	}
 
-	return raw, nil
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(records); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
-func isNullableNumericField(key string) bool {
-	if key == "forecourts.location.latitude" || key == "forecourts.location.longitude" {
-		return true
-	}
-	return strings.HasPrefix(key, "forecourts.fuel_price.")
+// convertOptions extends the record conversion options with the settings
+// that only apply to the CLI's own output formats.
+type convertOptions struct {
+	fuel.ConvertOptions
+	delimiter rune
+	// flattenSep, if set, replaces the dots in CSV and TSV header names.
+	flattenSep string
+	// nullToken replaces empty values in numeric and boolean columns, and
+	// in the columns of Nulls, in CSV and TSV output, telling nulls apart from empty strings.
+	nullToken string
 }
 
 func parseFloat(raw string) (float64, error) {
@@ -237,41 +446,59 @@ func parseBool(raw string) (bool, error) {
 	return strconv.ParseBool(raw)
 }
 
-func setNestedValue(root map[string]any, path []string, value any) error {
-	if len(path) == 0 {
-		return errors.New("empty key path")
+// parseFileMode parses an octal permission string such as "0660".
+func parseFileMode(value string) (os.FileMode, error) {
+	parsed, err := strconv.ParseUint(value, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an octal mode", value)
 	}
-
-	current := root
-	for i := 0; i < len(path)-1; i++ {
-		segment := path[i]
-		if segment == "" {
-			return errors.New("empty key segment")
-		}
-		if next, ok := current[segment]; ok {
-			nested, ok := next.(map[string]any)
-			if !ok {
-				return fmt.Errorf("%s is not an object", strings.Join(path[:i+1], "."))
-			}
-			current = nested
-			continue
-		}
-		child := make(map[string]any)
-		current[segment] = child
-		current = child
+	if parsed > 0o777 {
+		return 0, fmt.Errorf("%q is out of range", value)
 	}
+	return os.FileMode(parsed), nil
+}
 
-	leaf := path[len(path)-1]
-	if leaf == "" {
-		return errors.New("empty key segment")
-	}
-	current[leaf] = value
+// stringList collects the values of a repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
 	return nil
 }
 
+// parseHeaders turns "Name: Value" strings into a header set. Later values
+// for the same name are added alongside earlier ones.
+func parseHeaders(values []string) (http.Header, error) {
+	headers := make(http.Header, len(values))
+	for _, value := range values {
+		name, content, ok := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("expected \"Name: Value\" but got %q", value)
+		}
+		headers.Add(name, strings.TrimSpace(content))
+	}
+	return headers, nil
+}
+
 func exitWithError(err error) {
 	fmt.Fprintln(os.Stderr, err)
-	os.Exit(1)
+	os.Exit(exitCode(err))
+}
+
+func exitCode(err error) int {
+	if errors.Is(err, context.Canceled) {
+		return exitInterrupted
+	}
+	var classified *exitError
+	if errors.As(err, &classified) {
+		return classified.code
+	}
+	return exitUsage
 }
 
 func getEnvDefault(key, fallback string) string {
@@ -281,3 +508,32 @@ func getEnvDefault(key, fallback string) string {
 	}
 	return value
 }
+
+// setFromEnv sets value from the environment variable key unless the flag
+// name was given on the command line. A -config file still overrides it,
+// as it would a default from getEnvDefault.
+func setFromEnv(flags *flag.FlagSet, value *string, name, key string) {
+	env, ok := os.LookupEnv(key)
+	if !ok {
+		return
+	}
+	explicit := false
+	flags.Visit(func(f *flag.Flag) {
+		explicit = explicit || f.Name == name
+	})
+	if !explicit {
+		*value = env
+	}
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import "testing"
+
+func TestDecodeCharset(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		charset string
+		want    string
+	}{
+		{"windows-1252", []byte("name,price\nCaf\xe9 \x80,\xa3129.9\n"), "windows-1252", "name,price\nCafé €,£129.9\n"},
+		{"latin1 alias", []byte("Caf\xe9"), "latin1", "Café"},
+		{"leading BOM", []byte("\ufeffname\n"), "utf-8", "name\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCharset(tt.payload, tt.charset)
+			if err != nil {
+				t.Fatalf("decodeCharset: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("decodeCharset = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCharsetUnknown(t *testing.T) {
+	if _, err := decodeCharset([]byte("name\n"), "klingon"); err == nil {
+		t.Error("decodeCharset accepted an unknown charset")
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// runManifest records the provenance of a successful run for archival
+// audits. Status is omitted when the data was read with -input.
+type runManifest struct {
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Status    int       `json:"status,omitempty"`
+	Output    string    `json:"output"`
+	Format    string    `json:"format"`
+	Bytes     int64     `json:"bytes"`
+	Rows      int       `json:"rows"`
+	SHA256    string    `json:"sha256"`
+	Version   string    `json:"version"`
+	Commit    string    `json:"commit"`
+}
+
+func writeManifest(path string, manifest runManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0o644)
+}
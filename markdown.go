@@ -0,0 +1,59 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// markdownWarnRows is the row count above which a Markdown table is
+// reported as too large to be useful.
+const markdownWarnRows = 500
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+
+// convertCSVToMarkdown writes the rows as a GitHub-flavoured Markdown table,
+// with prices and coordinates right-aligned.
+func convertCSVToMarkdown(payload []byte, opts convertOptions) ([]byte, error) {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	names := opts.OutputNames(header)
+	for i, name := range names {
+		names[i] = markdownEscaper.Replace(name)
+	}
+	writeMarkdownRow(&buf, names)
+	alignment := make([]string, len(header))
+	for i, key := range header {
+		alignment[i] = "---"
+		if opts.ColumnType(key) == fuel.TypeNumeric {
+			alignment[i] = "---:"
+		}
+	}
+	buf.WriteString("| " + strings.Join(alignment, " | ") + " |\n")
+
+	cells := make([]string, len(header))
+	for _, row := range rows {
+		for i := range header {
+			cells[i] = markdownEscaper.Replace(fieldAt(row, i))
+		}
+		writeMarkdownRow(&buf, cells)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeMarkdownRow(buf *bytes.Buffer, cells []string) {
+	buf.WriteString("|")
+	for _, cell := range cells {
+		buf.WriteString(" " + cell + " |")
+	}
+	buf.WriteByte('\n')
+}
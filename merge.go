@@ -0,0 +1,201 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// retailerFeed is the JSON document published by individual retailers under
+// the fuel price transparency scheme.
+type retailerFeed struct {
+	LastUpdated string         `json:"last_updated"`
+	Stations    []retailerSite `json:"stations"`
+}
+
+type retailerSite struct {
+	SiteID   string `json:"site_id"`
+	Brand    string `json:"brand"`
+	Address  string `json:"address"`
+	Postcode string `json:"postcode"`
+	Location struct {
+		Latitude  feedNumber `json:"latitude"`
+		Longitude feedNumber `json:"longitude"`
+	} `json:"location"`
+	Prices map[string]feedNumber `json:"prices"`
+}
+
+// feedNumber accepts a JSON number or a numeric string, as retailers
+// disagree on which to publish. An empty value stays empty.
+type feedNumber string
+
+func (n *feedNumber) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*n = ""
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*n = feedNumber(text)
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	*n = feedNumber(number)
+	return nil
+}
+
+// retailerFuelColumns maps retailer fuel codes onto the Fuel Finder price
+// columns.
+var retailerFuelColumns = map[string]string{
+	"E5":  fuel.PricePrefix + "E5",
+	"E10": fuel.PricePrefix + "E10",
+	"B7":  fuel.PricePrefix + "B7S",
+	"SDV": fuel.PricePrefix + "B7P",
+	"B10": fuel.PricePrefix + "B10",
+	"HVO": fuel.PricePrefix + "HVO",
+}
+
+func fetchRetailerFeed(ctx context.Context, client *http.Client, target string, opts fuel.FetchOptions) (*retailerFeed, error) {
+	result, err := fuel.FetchURL(ctx, client, target, opts)
+	if err != nil {
+		return nil, err
+	}
+	var feed retailerFeed
+	if err := json.Unmarshal(result.Payload, &feed); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", target, err)
+	}
+	return &feed, nil
+}
+
+// fetchRetailerFeeds fetches the retailer feeds with at most concurrency
+// requests in flight, returning them in the order of targets. The first
+// failure cancels the fetches still running and is returned.
+func fetchRetailerFeeds(ctx context.Context, client *http.Client, targets []string, opts fuel.FetchOptions, concurrency int) ([]*retailerFeed, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	feeds := make([]*retailerFeed, len(targets))
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	slots := make(chan struct{}, concurrency)
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-slots }()
+
+			feed, err := fetchRetailerFeed(ctx, client, target, opts)
+			if err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("merge %s: %w", target, err)
+					cancel()
+				})
+				return
+			}
+			feeds[i] = feed
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return feeds, nil
+}
+
+// mergeRetailerFeeds appends the retailer stations to the CSV payload as rows
+// shaped like the main feed. Sites already present, by site id, are skipped
+// so the main feed wins. It returns the merged CSV and the rows added.
+func mergeRetailerFeeds(payload []byte, feeds []*retailerFeed) ([]byte, int, error) {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		index[column] = i
+	}
+	idIndex, ok := index[siteIDColumn]
+	if !ok {
+		return nil, 0, fmt.Errorf("missing column %s", siteIDColumn)
+	}
+
+	seen := make(map[string]struct{}, len(rows))
+	for _, row := range rows {
+		seen[fieldAt(row, idIndex)] = struct{}{}
+	}
+
+	added := 0
+	for _, feed := range feeds {
+		for _, site := range feed.Stations {
+			if site.SiteID == "" {
+				continue
+			}
+			if _, ok := seen[site.SiteID]; ok {
+				continue
+			}
+			seen[site.SiteID] = struct{}{}
+
+			row := make([]string, len(header))
+			set := func(column, value string) {
+				if i, ok := index[column]; ok {
+					row[i] = value
+				}
+			}
+			set("forecourt_update_timestamp", feed.LastUpdated)
+			set(siteIDColumn, site.SiteID)
+			set(brandColumn, site.Brand)
+			set("forecourts.location.address_line_1", site.Address)
+			set(postcodeColumn, site.Postcode)
+			set(latitudeColumn, normalizeFeedNumber(site.Location.Latitude))
+			set(longitudeColumn, normalizeFeedNumber(site.Location.Longitude))
+			for fuel, price := range site.Prices {
+				if column, ok := retailerFuelColumns[fuel]; ok {
+					set(column, normalizeFeedNumber(price))
+				}
+			}
+
+			rows = append(rows, row)
+			added++
+		}
+	}
+
+	output, err := fuel.WriteCSV(header, rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return output, added, nil
+}
+
+func normalizeFeedNumber(n feedNumber) string {
+	value, err := parseFloat(string(n))
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
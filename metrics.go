@@ -0,0 +1,67 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// runMetrics is the state of a run exported for the node_exporter textfile
+// collector. A negative forecourt count means it is unknown, for example
+// when the fetch failed or upstream was unchanged.
+type runMetrics struct {
+	success    bool
+	forecourts int
+	brands     map[string]int
+	finished   time.Time
+}
+
+func writeMetrics(path string, m runMetrics) error {
+	return writeFileAtomic(path, formatMetrics(m), 0o644)
+}
+
+// formatMetrics renders m in the Prometheus text exposition format.
+func formatMetrics(m runMetrics) []byte {
+	var buf bytes.Buffer
+
+	success := 0
+	if m.success {
+		success = 1
+	}
+	writeGauge(&buf, "fuelfinder_fetch_success", "Whether the last fetch succeeded.")
+	fmt.Fprintf(&buf, "fuelfinder_fetch_success %d\n", success)
+
+	writeGauge(&buf, "fuelfinder_last_run_timestamp_seconds", "Unix time the last run finished.")
+	fmt.Fprintf(&buf, "fuelfinder_last_run_timestamp_seconds %d\n", m.finished.Unix())
+
+	if m.forecourts >= 0 {
+		writeGauge(&buf, "fuelfinder_forecourts_total", "Number of forecourts in the output.")
+		fmt.Fprintf(&buf, "fuelfinder_forecourts_total %d\n", m.forecourts)
+	}
+
+	if len(m.brands) > 0 {
+		writeGauge(&buf, "fuelfinder_brand_forecourts", "Number of forecourts in the output per brand.")
+		for _, brand := range slices.Sorted(maps.Keys(m.brands)) {
+			fmt.Fprintf(&buf, "fuelfinder_brand_forecourts{brand=\"%s\"} %d\n", escapeLabel(brand), m.brands[brand])
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func writeGauge(buf *bytes.Buffer, name, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
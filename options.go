@@ -0,0 +1,662 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"fuelfinder-archive/pkg/fuel"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// options holds the command-line flags, followed by the settings validate
+// derives from them.
+type options struct {
+	outPath          string
+	outputPath       string
+	splitBy          string
+	outDir           string
+	format           string
+	brands           string
+	numericColumns   string
+	stringColumns    string
+	boolColumns      string
+	wrap             string
+	metaList         stringList
+	hasFuels         stringList
+	postcodes        string
+	near             string
+	radiusKm         float64
+	inputPath        string
+	charset          string
+	sourceURL        string
+	proxyTemplate    string
+	auth             string
+	timeout          string
+	targetTimeout    time.Duration
+	proxy            string
+	retries          int
+	retryBase        time.Duration
+	userAgent        string
+	maxBytes         int64
+	maxRetryAfter    time.Duration
+	rateLimitRetries int
+	fallbackLast     bool
+	cacheMetaPath    string
+	dedupe           bool
+	dedupeKey        string
+	sortColumn       string
+	sortDesc         bool
+	columns          string
+	diffPath         string
+	deltaPath        string
+	showStats        bool
+	schemaPath       string
+	schemaAnyOrder   bool
+	checksum         string
+	mode             string
+	delimiter        string
+	trim             bool
+	upper            string
+	lower            string
+	priceUnit        string
+	jsonFlat         bool
+	compact          bool
+	indent           string
+	integers         bool
+	compress         bool
+	mergeURLs        stringList
+	concurrency      int
+	renameList       stringList
+	clientCert       string
+	clientKey        string
+	caCert           string
+	insecure         bool
+	headers          stringList
+	trace            bool
+	logLevel         string
+	verbose          bool
+	dryRun           bool
+	quiet            bool
+	skipBad          bool
+	minRows          int
+	require          string
+	requireMode      string
+	validateRanges   string
+	priceMin         float64
+	priceMax         float64
+	fixCoordSwap     bool
+	coordBoundsFlag  string
+	enrichRegions    bool
+	deriveAvail      bool
+	keepRaw          string
+	onlyIfChanged    bool
+	round            int
+	roundCoords      int
+	jsonOrder        string
+	flattenSep       string
+	nullToken        string
+	mkdir            bool
+	appendOutput     bool
+	timestampField   string
+	manifestPath     string
+	metricsPath      string
+	listColumns      bool
+	historyDB        string
+	historyStats     bool
+	spread           time.Duration
+	minInterval      time.Duration
+	fetchMarker      string
+	serveAddr        string
+	deadline         time.Duration
+	interval         time.Duration
+	validateOnly     bool
+	validateJSONPath string
+	showVersion      bool
+	configPath       string
+
+	formats        []string
+	outputs        []outputTarget
+	fileMode       os.FileMode
+	source         string
+	credentials    *url.Userinfo
+	clientTimeout  time.Duration
+	proxyURL       *url.URL
+	tlsConfig      *tls.Config
+	requestHeaders http.Header
+	bounds         coordBounds
+	renames        map[string]string
+	columnTypes    map[string]string
+	upperColumns   map[string]bool
+	lowerColumns   map[string]bool
+	schema         *columnSchema
+	filters        []rowFilter
+	convert        convertOptions
+}
+
+// newOptions registers every flag on flags, bound to the fields of the
+// returned options.
+func newOptions(flags *flag.FlagSet) *options {
+	o := &options{}
+	flags.StringVar(&o.outPath, "out", getEnvDefault("FUEL_OUT", "data.csv"), "output path for CSV data (- for stdout)")
+	flags.StringVar(&o.outputPath, "output", "", "output path for CSV data")
+	flags.StringVar(&o.splitBy, "split-by", "", "write one file per distinct value of this column into -out-dir")
+	flags.StringVar(&o.outDir, "out-dir", "", "directory for the files written by -split-by")
+	flags.StringVar(&o.format, "format", getEnvDefault("FUEL_FORMAT", "csv"), "output format: csv, tsv, json, ndjson, geojson, xml, yaml, sqlite, parquet, xlsx or md; comma-separate several to write each next to -out")
+	flags.StringVar(&o.brands, "brand", "", "comma-separated list of brands to keep")
+	flags.StringVar(&o.numericColumns, "numeric", "", "comma-separated columns typed as nullable numbers, overriding the built-in rules")
+	flags.StringVar(&o.stringColumns, "string", "", "comma-separated columns always typed as strings")
+	flags.StringVar(&o.boolColumns, "bool", "", "comma-separated columns typed as nullable booleans")
+	flags.StringVar(&o.wrap, "wrap", "", "nest each JSON or NDJSON record under this key")
+	flags.Var(&o.metaList, "meta", "static key=value field added next to the -wrap key (repeatable)")
+	flags.Var(&o.hasFuels, "has-fuel", "keep only forecourts with a price for this fuel, such as E10 (repeatable, all must match)")
+	flags.StringVar(&o.postcodes, "postcode", "", "comma-separated list of postcode prefixes to keep")
+	flags.StringVar(&o.near, "near", "", "keep forecourts near this lat,long point")
+	flags.Float64Var(&o.radiusKm, "radius-km", 0, "radius in kilometres used with -near")
+	flags.StringVar(&o.inputPath, "input", "", "read CSV from this local file instead of fetching it")
+	flags.StringVar(&o.charset, "charset", "", "transcode the source from this character set (e.g. windows-1252) to UTF-8")
+	flags.StringVar(&o.sourceURL, "url", fuel.DefaultURL, "source URL for the fuel CSV")
+	flags.StringVar(&o.proxyTemplate, "proxy-template", os.Getenv("FUEL_PROXY_TEMPLATE"), "comma-separated fallback proxy templates tried in order after -url")
+	flags.StringVar(&o.auth, "auth", "", "basic auth credentials for the source URL as user:pass")
+	flags.StringVar(&o.timeout, "timeout", getEnvDefault("FUEL_TIMEOUT", "30s"), "HTTP client timeout")
+	flags.DurationVar(&o.targetTimeout, "per-target-timeout", 0, "timeout for each attempt against the source or a proxy target (0 uses only -timeout)")
+	flags.StringVar(&o.proxy, "proxy", "", "forward proxy URL (http, https or socks5); defaults to HTTP_PROXY/HTTPS_PROXY")
+	flags.IntVar(&o.retries, "retries", 3, "number of retries for transient fetch errors")
+	flags.DurationVar(&o.retryBase, "retry-base", time.Second, "base delay for exponential retry backoff")
+	flags.StringVar(&o.userAgent, "user-agent", getEnvDefault("FUEL_USER_AGENT", fuel.DefaultUserAgent), "User-Agent header sent upstream; empty omits it")
+	flags.Int64Var(&o.maxBytes, "max-bytes", 256<<20, "largest response body accepted, after decompression (0 for no limit)")
+	flags.DurationVar(&o.maxRetryAfter, "max-retry-after", 5*time.Minute, "longest Retry-After wait honoured when rate limited (0 for no limit)")
+	flags.IntVar(&o.rateLimitRetries, "rate-limit-retries", 3, "number of retries for 429 responses, counted apart from -retries")
+	flags.BoolVar(&o.fallbackLast, "fallback-last", false, "keep the existing output and exit 0 if every fetch fails")
+	flags.StringVar(&o.cacheMetaPath, "cache-meta", "", "path to the ETag/Last-Modified sidecar (default <out>.meta)")
+	flags.BoolVar(&o.dedupe, "dedupe", false, "drop duplicate forecourts, keeping the first occurrence")
+	flags.StringVar(&o.dedupeKey, "dedupe-key", siteIDColumn, "column used to detect duplicates with -dedupe")
+	flags.StringVar(&o.sortColumn, "sort", "", "column used to sort rows before output")
+	flags.BoolVar(&o.sortDesc, "sort-desc", false, "sort in descending order with -sort")
+	flags.StringVar(&o.columns, "columns", "", "comma-separated list of columns to keep in the output")
+	flags.StringVar(&o.diffPath, "diff", "", "previous CSV snapshot to compare against, reporting changes to stderr")
+	flags.StringVar(&o.deltaPath, "delta", "", "previous CSV snapshot to compute per-fuel <price>_delta columns against")
+	flags.BoolVar(&o.showStats, "stats", false, "print summary statistics to stderr")
+	flags.StringVar(&o.schemaPath, "schema", "", "file listing the expected header columns, one per line")
+	flags.BoolVar(&o.schemaAnyOrder, "schema-any-order", false, "tolerate reordered columns when checking -schema")
+	flags.StringVar(&o.checksum, "checksum", "", "write a <out>.<algorithm> checksum sidecar: sha256, sha1 or md5")
+	flags.StringVar(&o.mode, "mode", "0644", "octal permissions for the output file")
+	flags.StringVar(&o.delimiter, "delimiter", ",", "single-character field delimiter for CSV output")
+	flags.BoolVar(&o.trim, "trim", false, "trim and collapse whitespace in string values of typed output")
+	flags.StringVar(&o.upper, "upper", "", "comma-separated columns whose string values are uppercased in typed output")
+	flags.StringVar(&o.lower, "lower", "", "comma-separated columns whose string values are lowercased in typed output")
+	flags.StringVar(&o.priceUnit, "price-unit", "", "convert fuel prices in typed output: pence (as published) or pounds")
+	flags.BoolVar(&o.jsonFlat, "json-flat", false, "keep dotted column names as top-level JSON keys instead of nesting them")
+	flags.BoolVar(&o.compact, "compact", false, "write JSON and GeoJSON without indentation")
+	flags.StringVar(&o.indent, "indent", "  ", "indent string for JSON and GeoJSON output (spaces or tabs)")
+	flags.BoolVar(&o.integers, "integers", true, "emit whole numbers without a decimal point as integers")
+	flags.BoolVar(&o.compress, "gzip", getEnvBool("FUEL_GZIP", false), "gzip compress the output file")
+	flags.Var(&o.mergeURLs, "merge", "additional retailer JSON feed URL to merge, deduplicated by site id (repeatable)")
+	flags.IntVar(&o.concurrency, "concurrency", 4, "number of -merge feeds fetched at once")
+	flags.Var(&o.renameList, "rename", "rename a column in the output as old=new (repeatable)")
+	flags.StringVar(&o.clientCert, "client-cert", "", "PEM client certificate for mutual TLS (requires -client-key)")
+	flags.StringVar(&o.clientKey, "client-key", "", "PEM private key for -client-cert")
+	flags.StringVar(&o.caCert, "ca-cert", "", "PEM file of CA certificates to trust in addition to the system roots")
+	flags.BoolVar(&o.insecure, "insecure", false, "skip TLS certificate verification (debugging only)")
+	flags.Var(&o.headers, "header", "extra request header as \"Name: Value\" (repeatable)")
+	flags.BoolVar(&o.trace, "trace", false, "print a timing breakdown of each request and of processing to stderr")
+	flags.StringVar(&o.logLevel, "log-level", "warn", "log level: debug, info, warn or error")
+	flags.BoolVar(&o.verbose, "verbose", false, "log each step to stderr (same as -log-level debug)")
+	flags.BoolVar(&o.dryRun, "dry-run", false, "fetch, validate and convert without writing any files")
+	flags.BoolVar(&o.quiet, "quiet", false, "suppress all output on stderr except a fatal error")
+	flags.BoolVar(&o.skipBad, "skip-bad-rows", false, "drop rows with the wrong number of fields instead of failing")
+	flags.IntVar(&o.minRows, "min-rows", 0, "refuse to write the output if the feed has fewer rows than this")
+	flags.StringVar(&o.require, "require", "", "comma-separated columns that must be non-empty")
+	flags.StringVar(&o.requireMode, "require-mode", "drop", "what to do with rows missing a -require column: drop or fail")
+	flags.StringVar(&o.validateRanges, "validate-ranges", "", "check coordinate and price ranges: drop (warn and drop bad rows) or fail")
+	flags.Float64Var(&o.priceMin, "price-min", 50, "lowest fuel price in pence accepted by -validate-ranges")
+	flags.Float64Var(&o.priceMax, "price-max", 500, "highest fuel price in pence accepted by -validate-ranges")
+	flags.BoolVar(&o.fixCoordSwap, "fix-coord-swap", false, "swap latitude and longitude of rows outside -coord-bounds that fit once swapped")
+	flags.StringVar(&o.coordBoundsFlag, "coord-bounds", defaultCoordBounds, "minlat,minlon,maxlat,maxlon box of plausible locations for -fix-coord-swap")
+	flags.BoolVar(&o.enrichRegions, "enrich-region", false, "add a region column looked up from each site's postcode area")
+	flags.BoolVar(&o.deriveAvail, "derive-availability", false, "add has_<fuel> columns saying whether each fuel has a price")
+	flags.StringVar(&o.keepRaw, "keep-raw", "", "also write the fetched CSV, before any decoding or filtering, to this path")
+	flags.BoolVar(&o.onlyIfChanged, "only-if-changed", false, "leave the output untouched when the new content has the same SHA-256 as the existing file")
+	flags.IntVar(&o.round, "round", -1, "round prices and other numeric values, except coordinates, to this many decimal places (-1 leaves them as published)")
+	flags.IntVar(&o.roundCoords, "round-coords", -1, "round latitude and longitude to this many decimal places (-1 leaves them as published)")
+	flags.StringVar(&o.jsonOrder, "json-order", "alpha", "key order in JSON and NDJSON output: alpha or source (header column order)")
+	flags.StringVar(&o.flattenSep, "flatten-sep", "", "replace the dots in CSV and TSV header names with this separator (e.g. _)")
+	flags.StringVar(&o.nullToken, "null-token", "", "write empty numeric and boolean values as this token (e.g. \\N) in CSV and TSV output")
+	flags.BoolVar(&o.mkdir, "mkdir", false, "create missing parent directories of the output and sidecar files")
+	flags.BoolVar(&o.appendOutput, "append", false, "append ndjson records tagged with fetched_at to the output instead of replacing it")
+	flags.StringVar(&o.timestampField, "timestamp-field", "", "add a column with this name holding the fetch time (RFC3339) to every record")
+	flags.StringVar(&o.manifestPath, "manifest", "", "write a JSON manifest describing the run to this path")
+	flags.StringVar(&o.metricsPath, "metrics", "", "write Prometheus textfile metrics to this path")
+	flags.BoolVar(&o.listColumns, "list-columns", false, "print the feed's columns to stdout, marking numeric ones, and exit without writing")
+	flags.StringVar(&o.historyDB, "history-db", "", "record every price in this embedded key-value store, building a per-site time series")
+	flags.BoolVar(&o.historyStats, "history-stats", false, "add <price>_min and <price>_max columns from -history-db")
+	flags.DurationVar(&o.spread, "spread", 0, "sleep a random time below this before the first fetch, so archivers on the same schedule do not all hit at once")
+	flags.DurationVar(&o.minInterval, "min-interval", 0, "never fetch more often than this, even across restarts, waiting out the remainder")
+	flags.StringVar(&o.fetchMarker, "fetch-marker", "", "file recording the last fetch time for -min-interval (default <out>.last-fetch)")
+	flags.StringVar(&o.serveAddr, "serve", "", "serve the latest data as /data.csv and /data.json on this address (e.g. :8080), refreshing every -interval")
+	flags.DurationVar(&o.deadline, "deadline", 0, "wall-clock budget for the whole process, retries included, after which it exits with status 124 (0 means none)")
+	flags.DurationVar(&o.interval, "interval", 0, "keep running, fetching and writing again after this long (0 runs once)")
+	flags.BoolVar(&o.validateOnly, "validate-only", false, "fetch and check the feed, print a JSON report to stdout and exit non-zero on any problem, without writing output")
+	flags.StringVar(&o.validateJSONPath, "validate-json", "", "check an existing JSON output file against the bundled schema and exit")
+	flags.BoolVar(&o.showVersion, "version", false, "print version information and exit")
+	flags.StringVar(&o.configPath, "config", "", "YAML file of flag defaults; command-line flags take precedence")
+	return o
+}
+
+// applyDefaults fills the settings read after parsing: the environment
+// variables that may carry credentials, and then any -config file. Neither
+// overrides a flag given on the command line.
+func (o *options) applyDefaults(flags *flag.FlagSet) error {
+	// These are not flag defaults, which -h would print.
+	setFromEnv(flags, &o.sourceURL, "url", "FUEL_URL")
+	setFromEnv(flags, &o.auth, "auth", "FUEL_AUTH")
+
+	if o.configPath != "" {
+		if err := applyConfigFile(flags, o.configPath); err != nil {
+			return fmt.Errorf("invalid -config: %w", err)
+		}
+	}
+	return nil
+}
+
+// validate checks the flags against each other, fills in defaults that
+// depend on other flags and parses the derived settings.
+func (o *options) validate() error {
+	var err error
+	if o.outputPath != "" {
+		o.outPath = o.outputPath
+	}
+
+	o.formats = splitList(o.format)
+	if len(o.formats) == 0 {
+		return errors.New("format cannot be empty")
+	}
+	for i, f := range o.formats {
+		if slices.Index(o.formats, f) != i {
+			return fmt.Errorf("-format lists %s twice", f)
+		}
+	}
+	if len(o.formats) == 1 && o.formats[0] != "csv" && o.outPath == "data.csv" {
+		o.outPath = "data." + o.formats[0]
+	}
+	if len(o.formats) > 1 {
+		switch {
+		case o.outPath == "-":
+			return errors.New("several formats require an output file")
+		case o.manifestPath != "":
+			return errors.New("-manifest cannot be used with several formats")
+		}
+	}
+
+	if o.outPath == "" {
+		return errors.New("output path cannot be empty")
+	}
+
+	if o.charset != "" {
+		if _, err := htmlindex.Get(o.charset); err != nil {
+			return fmt.Errorf("invalid -charset: unknown charset %q", o.charset)
+		}
+	}
+
+	o.fileMode, err = parseFileMode(o.mode)
+	if err != nil {
+		return fmt.Errorf("invalid -mode: %w", err)
+	}
+
+	if o.splitBy != "" {
+		if o.outDir == "" {
+			return errors.New("-split-by requires -out-dir")
+		}
+		if o.manifestPath != "" {
+			return errors.New("-manifest cannot be used with -split-by")
+		}
+	}
+
+	if o.checksum != "" {
+		if _, err := newChecksumHash(o.checksum); err != nil {
+			return err
+		}
+		if o.outPath == "-" {
+			return errors.New("-checksum requires an output file")
+		}
+	}
+
+	if o.onlyIfChanged {
+		switch {
+		case o.outPath == "-" || isS3URL(o.outPath):
+			return errors.New("-only-if-changed requires a local output file")
+		case slices.Contains(o.formats, "sqlite"), o.appendOutput, o.splitBy != "":
+			return errors.New("-only-if-changed cannot be used with -format sqlite, -append or -split-by")
+		}
+	}
+
+	if isS3URL(o.outPath) {
+		if _, _, err := parseS3URL(o.outPath); err != nil {
+			return fmt.Errorf("invalid -out: %w", err)
+		}
+		switch {
+		case slices.Contains(o.formats, "sqlite"):
+			return errors.New("-format sqlite cannot be written to S3")
+		case o.appendOutput, o.checksum != "":
+			return errors.New("-append and -checksum cannot be used with an S3 output")
+		}
+	}
+
+	if o.appendOutput {
+		switch {
+		case len(o.formats) != 1 || o.formats[0] != "ndjson":
+			return errors.New("-append requires -format ndjson")
+		case o.outPath == "-":
+			return errors.New("-append requires an output file")
+		case o.compress, o.splitBy != "", o.checksum != "", o.manifestPath != "":
+			return errors.New("-append cannot be used with -gzip, -split-by, -checksum or -manifest")
+		}
+		if o.timestampField == "" {
+			o.timestampField = "fetched_at"
+		}
+	}
+
+	if o.compress && o.outPath != "-" && !strings.HasSuffix(o.outPath, ".gz") {
+		o.outPath += ".gz"
+	}
+	o.outputs = outputTargets(o.formats, o.outPath, o.compress)
+
+	switch {
+	case o.keepRaw == "-":
+		return errors.New("-keep-raw requires a file path")
+	case o.keepRaw != "" && slices.ContainsFunc(o.outputs, func(target outputTarget) bool { return target.path == o.keepRaw }):
+		return errors.New("-keep-raw cannot be the same path as an output")
+	}
+
+	comma, err := parseDelimiter(o.delimiter)
+	if err != nil {
+		return fmt.Errorf("invalid -delimiter: %w", err)
+	}
+	if o.round > 15 || o.roundCoords > 15 {
+		return errors.New("-round and -round-coords accept at most 15 decimal places")
+	}
+	if o.flattenSep != "" {
+		if !slices.Contains(o.formats, "csv") && !slices.Contains(o.formats, "tsv") {
+			return errors.New("-flatten-sep only applies to -format csv or tsv")
+		}
+		if strings.ContainsAny(o.flattenSep, "\"\r\n") {
+			return errors.New("invalid -flatten-sep: quotes and line breaks are not allowed")
+		}
+	}
+	if o.nullToken != "" && !slices.Contains(o.formats, "csv") && !slices.Contains(o.formats, "tsv") {
+		return errors.New("-null-token only applies to -format csv or tsv")
+	}
+	if comma != ',' && !slices.Contains(o.formats, "csv") {
+		return errors.New("-delimiter only applies to -format csv")
+	}
+
+	switch o.priceUnit {
+	case "", fuel.PriceUnitPence, fuel.PriceUnitPounds:
+	default:
+		return fmt.Errorf("unsupported -price-unit %q (expected pence or pounds)", o.priceUnit)
+	}
+
+	if strings.Trim(o.indent, " \t") != "" {
+		return fmt.Errorf("invalid -indent %q: only spaces and tabs are allowed", o.indent)
+	}
+	if o.compact {
+		o.indent = ""
+	}
+
+	for _, f := range o.formats {
+		switch f {
+		case "csv", "tsv", "json", "ndjson", "geojson", "xml", "yaml", "parquet", "xlsx", "md":
+		case "sqlite":
+			if o.outPath == "-" || o.compress {
+				return errors.New("sqlite output must be written to a file without -gzip")
+			}
+		default:
+			return fmt.Errorf("unsupported format: %s", f)
+		}
+	}
+
+	if o.cacheMetaPath == "" && o.outPath != "-" && o.splitBy == "" && !isS3URL(o.outPath) {
+		o.cacheMetaPath = o.outPath + ".meta"
+	}
+	if o.minInterval < 0 {
+		return errors.New("-min-interval cannot be negative")
+	}
+	if o.historyStats && o.historyDB == "" {
+		return errors.New("-history-stats requires -history-db")
+	}
+	if o.spread < 0 {
+		return errors.New("-spread cannot be negative")
+	}
+	if o.minInterval > 0 && o.fetchMarker == "" {
+		if o.outPath == "-" || o.splitBy != "" || isS3URL(o.outPath) {
+			return errors.New("-min-interval requires -fetch-marker when the output is not a local file")
+		}
+		o.fetchMarker = o.outPath + ".last-fetch"
+	}
+
+	if strings.TrimSpace(o.sourceURL) == "" {
+		return errors.New("source URL cannot be empty")
+	}
+
+	o.source, o.credentials, err = splitURLCredentials(o.sourceURL)
+	if err != nil {
+		return fmt.Errorf("invalid -url: %w", err)
+	}
+	if o.auth != "" {
+		user, password, ok := strings.Cut(o.auth, ":")
+		if !ok || user == "" {
+			return errors.New("invalid -auth: expected user:pass")
+		}
+		o.credentials = url.UserPassword(user, password)
+	}
+
+	o.clientTimeout, err = time.ParseDuration(o.timeout)
+	if err != nil {
+		return fmt.Errorf("invalid -timeout: %w", err)
+	}
+	if o.clientTimeout <= 0 {
+		return errors.New("-timeout must be positive")
+	}
+
+	if o.proxy != "" {
+		o.proxyURL, err = parseProxyURL(o.proxy)
+		if err != nil {
+			return fmt.Errorf("invalid -proxy: %w", err)
+		}
+	}
+
+	o.tlsConfig, err = loadTLSConfig(o.clientCert, o.clientKey, o.caCert, o.insecure)
+	if err != nil {
+		return err
+	}
+
+	if o.maxBytes < 0 {
+		return errors.New("-max-bytes cannot be negative")
+	}
+
+	if o.concurrency < 1 {
+		return errors.New("-concurrency must be at least 1")
+	}
+
+	if o.retries < 0 {
+		return errors.New("-retries cannot be negative")
+	}
+	if o.targetTimeout < 0 {
+		return errors.New("-per-target-timeout cannot be negative")
+	}
+	if o.minRows < 0 {
+		return errors.New("-min-rows cannot be negative")
+	}
+	if o.interval < 0 {
+		return errors.New("-interval cannot be negative")
+	}
+	if o.deadline < 0 {
+		return errors.New("-deadline cannot be negative")
+	}
+	if o.serveAddr != "" {
+		switch {
+		case o.splitBy != "", o.appendOutput, o.dryRun, o.listColumns, o.onlyIfChanged:
+			return errors.New("-serve cannot be used with -split-by, -append, -dry-run, -list-columns or -only-if-changed")
+		}
+		if o.interval == 0 {
+			o.interval = defaultServeInterval
+		}
+	}
+	if o.validateOnly && (o.interval > 0 || o.serveAddr != "" || o.listColumns) {
+		return errors.New("-validate-only cannot be used with -interval, -serve or -list-columns")
+	}
+	if o.listColumns && o.interval > 0 {
+		return errors.New("-list-columns cannot be used with -interval")
+	}
+	if o.maxRetryAfter < 0 {
+		return errors.New("-max-retry-after cannot be negative")
+	}
+	if o.rateLimitRetries < 0 {
+		return errors.New("-rate-limit-retries cannot be negative")
+	}
+
+	switch o.requireMode {
+	case "drop", "fail":
+	default:
+		return fmt.Errorf("unsupported -require-mode %q (expected drop or fail)", o.requireMode)
+	}
+
+	switch o.validateRanges {
+	case "", "drop", "fail":
+	default:
+		return fmt.Errorf("unsupported -validate-ranges %q (expected drop or fail)", o.validateRanges)
+	}
+	if o.priceMin > o.priceMax {
+		return errors.New("-price-min cannot exceed -price-max")
+	}
+	o.bounds, err = parseCoordBounds(o.coordBoundsFlag)
+	if err != nil {
+		return fmt.Errorf("invalid -coord-bounds: %w", err)
+	}
+
+	o.renames, err = parseRenames(o.renameList)
+	if err != nil {
+		return fmt.Errorf("invalid -rename: %w", err)
+	}
+	if slices.Contains(o.formats, "xml") {
+		for _, from := range slices.Sorted(maps.Keys(o.renames)) {
+			if err := checkXMLName(o.renames[from]); err != nil {
+				return fmt.Errorf("invalid -rename for -format xml: %w", err)
+			}
+		}
+	}
+
+	switch o.jsonOrder {
+	case "alpha":
+	case "source":
+		if !slices.Contains(o.formats, "json") && !slices.Contains(o.formats, "ndjson") {
+			return errors.New("-json-order only applies to -format json or ndjson")
+		}
+	default:
+		return fmt.Errorf("unsupported -json-order %q (expected alpha or source)", o.jsonOrder)
+	}
+	if o.wrap != "" && !slices.Contains(o.formats, "json") && !slices.Contains(o.formats, "ndjson") {
+		return errors.New("-wrap only applies to -format json or ndjson")
+	}
+	if len(o.metaList) > 0 && o.wrap == "" {
+		return errors.New("-meta requires -wrap")
+	}
+	meta, err := parseMeta(o.metaList, o.wrap)
+	if err != nil {
+		return fmt.Errorf("invalid -meta: %w", err)
+	}
+
+	o.columnTypes, err = parseColumnTypes(map[string]string{
+		fuel.TypeNumeric: o.numericColumns,
+		fuel.TypeString:  o.stringColumns,
+		fuel.TypeBool:    o.boolColumns,
+	})
+	if err != nil {
+		return err
+	}
+
+	o.upperColumns, o.lowerColumns = columnSet(o.upper), columnSet(o.lower)
+	for column := range o.upperColumns {
+		if o.lowerColumns[column] {
+			return fmt.Errorf("column %s cannot be in both -upper and -lower", column)
+		}
+	}
+
+	o.requestHeaders, err = parseHeaders(o.headers)
+	if err != nil {
+		return fmt.Errorf("invalid -header: %w", err)
+	}
+
+	if o.schemaPath != "" {
+		o.schema, err = loadSchema(o.schemaPath, o.schemaAnyOrder)
+		if err != nil {
+			return fmt.Errorf("load schema: %w", err)
+		}
+	}
+
+	if len(o.hasFuels) > 0 {
+		o.filters = append(o.filters, fuelFilter(o.hasFuels))
+	}
+	if list := splitList(o.brands); len(list) > 0 {
+		o.filters = append(o.filters, brandFilter(list))
+	}
+	if list := splitList(o.postcodes); len(list) > 0 {
+		o.filters = append(o.filters, postcodeFilter(list))
+	}
+	if o.near != "" {
+		lat, lon, err := parseCoordinates(o.near)
+		if err != nil {
+			return fmt.Errorf("invalid -near: %w", err)
+		}
+		if o.radiusKm <= 0 {
+			return errors.New("-radius-km must be positive when -near is set")
+		}
+		o.filters = append(o.filters, nearFilter(lat, lon, o.radiusKm))
+	}
+
+	// Sites without a known postcode area have no region, which the typed
+	// formats write as null.
+	var nulls map[string]bool
+	if o.enrichRegions {
+		nulls = map[string]bool{regionColumn: true}
+	}
+
+	o.convert = convertOptions{
+		ConvertOptions: fuel.ConvertOptions{
+			Integers:         o.integers,
+			Flat:             o.jsonFlat,
+			Renames:          o.renames,
+			Indent:           o.indent,
+			PriceUnit:        o.priceUnit,
+			Trim:             o.trim,
+			Upper:            o.upperColumns,
+			Lower:            o.lowerColumns,
+			Types:            o.columnTypes,
+			Nulls:            nulls,
+			SourceOrder:      o.jsonOrder == "source",
+			Wrap:             o.wrap,
+			Meta:             meta,
+			RoundNumbers:     o.round >= 0,
+			Decimals:         o.round,
+			RoundCoordinates: o.roundCoords >= 0,
+			CoordDecimals:    o.roundCoords,
+		},
+		delimiter:  comma,
+		flattenSep: o.flattenSep,
+		nullToken:  o.nullToken,
+	}
+	return nil
+}
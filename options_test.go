@@ -0,0 +1,131 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// parseOptions registers the flags on a fresh set and parses args, as main
+// does for the command line.
+func parseOptions(t *testing.T, args ...string) *options {
+	t.Helper()
+	flags := flag.NewFlagSet("fuelfinder-archive", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	o := newOptions(flags)
+	if err := flags.Parse(args); err != nil {
+		t.Fatalf("parse %q: %v", args, err)
+	}
+	if err := o.applyDefaults(flags); err != nil {
+		t.Fatalf("apply defaults: %v", err)
+	}
+	return o
+}
+
+func TestValidateDerivesOutputPath(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantOut  string
+		wantMeta string
+	}{
+		{nil, "data.csv", "data.csv.meta"},
+		{[]string{"-format", "json"}, "data.json", "data.json.meta"},
+		{[]string{"-format", "json", "-gzip"}, "data.json.gz", "data.json.gz.meta"},
+		{[]string{"-output", "prices.csv"}, "prices.csv", "prices.csv.meta"},
+		{[]string{"-out", "-"}, "-", ""},
+	}
+	for _, tt := range tests {
+		o := parseOptions(t, tt.args...)
+		if err := o.validate(); err != nil {
+			t.Errorf("validate %q: %v", tt.args, err)
+			continue
+		}
+		if o.outPath != tt.wantOut || o.cacheMetaPath != tt.wantMeta {
+			t.Errorf("validate %q: out %q, cache meta %q; want %q, %q", tt.args, o.outPath, o.cacheMetaPath, tt.wantOut, tt.wantMeta)
+		}
+	}
+}
+
+func TestValidateRejectsConflicts(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-format", "bogus"}, "unsupported format: bogus"},
+		{[]string{"-format", "csv,csv"}, "-format lists csv twice"},
+		{[]string{"-format", "json,csv", "-out", "-"}, "several formats require an output file"},
+		{[]string{"-split-by", "forecourts.brand_name"}, "-split-by requires -out-dir"},
+		{[]string{"-append"}, "-append requires -format ndjson"},
+		{[]string{"-round", "16"}, "at most 15 decimal places"},
+		{[]string{"-auth", "nopassword"}, "invalid -auth"},
+		{[]string{"-upper", "a", "-lower", "a"}, "cannot be in both -upper and -lower"},
+		{[]string{"-near", "51.5,-0.1"}, "-radius-km must be positive"},
+		{[]string{"-format", "xml", "-rename", "forecourts.brand_name=brand name"}, `"brand name" is not a valid XML element name`},
+	}
+	for _, tt := range tests {
+		err := parseOptions(t, tt.args...).validate()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("validate %q = %v, want an error containing %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestApplyDefaultsReadsCredentialsFromEnvironment(t *testing.T) {
+	t.Setenv("FUEL_AUTH", "alice:s3cret")
+	t.Setenv("FUEL_URL", "https://bob:pw@example.com/prices.csv")
+
+	flags := flag.NewFlagSet("fuelfinder-archive", flag.ContinueOnError)
+	newOptions(flags)
+	for _, name := range []string{"auth", "url"} {
+		if value := flags.Lookup(name).DefValue; strings.Contains(value, "s3cret") || strings.Contains(value, "bob") {
+			t.Errorf("-%s default %q shows credentials from the environment", name, value)
+		}
+	}
+
+	o := parseOptions(t)
+	if o.auth != "alice:s3cret" || o.sourceURL != "https://bob:pw@example.com/prices.csv" {
+		t.Errorf("auth %q, url %q; want the environment values", o.auth, o.sourceURL)
+	}
+
+	o = parseOptions(t, "-auth", "carol:pw", "-url", "https://example.org/prices.csv")
+	if o.auth != "carol:pw" || o.sourceURL != "https://example.org/prices.csv" {
+		t.Errorf("auth %q, url %q; want the command-line values", o.auth, o.sourceURL)
+	}
+}
+
+func TestRunnerWritesInput(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "feed.csv")
+	payload := "forecourts.node_id,forecourts.brand_name,forecourts.fuel_price.E10\n" +
+		"abc123,TESCO,129.9\n" +
+		"def456,SHELL,\n"
+	if err := os.WriteFile(input, []byte(payload), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "prices.json")
+
+	o := parseOptions(t, "-input", input, "-out", out, "-format", "json", "-compact", "-brand", "TESCO", "-price-unit", "pounds")
+	if err := o.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if err := newRunner(o, io.Discard).run(context.Background()); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"forecourts":{"brand_name":"TESCO","fuel_price":{"E10":1.299},"node_id":"abc123"}}]`
+	if strings.TrimSpace(string(got)) != want {
+		t.Errorf("output = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,332 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// outputSink receives the converted output. Nothing written is visible at
+// the destination until Commit; Abort discards it.
+type outputSink interface {
+	io.Writer
+	Commit() error
+	Abort()
+}
+
+func openOutput(path string, perm os.FileMode) (outputSink, error) {
+	if path == "-" {
+		return stdoutSink{}, nil
+	}
+	return createAtomicFile(path, perm)
+}
+
+// appendFile buffers the output and appends it to path in a single write on
+// Commit, holding an exclusive lock on the file so that concurrent runs never
+// interleave partial lines.
+type appendFile struct {
+	bytes.Buffer
+	path string
+	perm os.FileMode
+}
+
+func (f *appendFile) Commit() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, f.perm)
+	if err != nil {
+		return err
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return fmt.Errorf("lock %s: %w", f.path, err)
+	}
+	if _, err := file.Write(f.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	// Closing the file releases the lock.
+	return file.Close()
+}
+
+func (f *appendFile) Abort() { f.Reset() }
+
+type stdoutSink struct{}
+
+func (stdoutSink) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (stdoutSink) Commit() error               { return nil }
+func (stdoutSink) Abort()                      {}
+
+// atomicFile writes to a temporary file in the same directory as path and
+// renames it into place on Commit, so readers never see a partial file.
+// Existing non-regular files such as /dev/null or a named pipe are written
+// in place instead.
+type atomicFile struct {
+	*os.File
+	path   string
+	perm   os.FileMode
+	direct bool
+}
+
+func createAtomicFile(path string, perm os.FileMode) (*atomicFile, error) {
+	if info, err := os.Stat(path); err == nil && !info.Mode().IsRegular() {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, perm)
+		if err != nil {
+			return nil, err
+		}
+		return &atomicFile{File: file, path: path, perm: perm, direct: true}, nil
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: file, path: path, perm: perm}, nil
+}
+
+func (f *atomicFile) Commit() error {
+	if f.direct {
+		return f.Close()
+	}
+	if err := f.Chmod(f.perm); err != nil {
+		f.Abort()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Abort()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+func (f *atomicFile) Abort() {
+	f.Close()
+	if !f.direct {
+		os.Remove(f.Name())
+	}
+}
+
+// outputTarget is one requested format and the path it is written to.
+type outputTarget struct {
+	format string
+	path   string
+}
+
+// outputTargets pairs each format with its path. A single format is written
+// to out; several are written side by side, replacing the extension of out
+// (after any .gz) with each format's own, so data.csv becomes data.csv and
+// data.json.
+func outputTargets(formats []string, out string, compress bool) []outputTarget {
+	if len(formats) == 1 {
+		return []outputTarget{{format: formats[0], path: out}}
+	}
+	base := strings.TrimSuffix(out, ".gz")
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	targets := make([]outputTarget, len(formats))
+	for i, format := range formats {
+		path := base + "." + format
+		if compress {
+			path += ".gz"
+		}
+		targets[i] = outputTarget{format: format, path: path}
+	}
+	return targets
+}
+
+// outputsExist reports whether every target has been written before.
+func outputsExist(targets []outputTarget) bool {
+	for _, target := range targets {
+		if !fileExists(target.path) {
+			return false
+		}
+	}
+	return true
+}
+
+// makeParentDirs creates the missing parent directories of each local path,
+// skipping empty paths and "-".
+func makeParentDirs(paths ...string) error {
+	for _, path := range paths {
+		if path == "" || path == "-" || isS3URL(path) {
+			continue
+		}
+		dir := filepath.Dir(path)
+		if _, err := os.Stat(dir); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		slog.Info("created directory", "path", dir)
+	}
+	return nil
+}
+
+// writeFileAtomic writes payload to path through an atomicFile.
+func writeFileAtomic(path string, payload []byte, perm os.FileMode) error {
+	file, err := createAtomicFile(path, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(payload); err != nil {
+		file.Abort()
+		return err
+	}
+	return file.Commit()
+}
+
+// writeFormatted converts payload to format and writes it to w, gzip
+// compressing it first when asked. JSON is streamed record by record; the
+// other formats are converted in full before being written. Conversion
+// failures are classified as validation errors.
+func writeFormatted(w io.Writer, format string, payload []byte, compress bool, opts convertOptions) error {
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(w)
+		w = gz
+	}
+
+	if format == "json" {
+		if err := fuel.StreamJSON(w, payload, opts.ConvertOptions); err != nil {
+			return validationError(fmt.Errorf("convert to JSON: %w", err))
+		}
+	} else {
+		output, err := convertPayload(format, payload, opts)
+		if err != nil {
+			return validationError(err)
+		}
+		if _, err := w.Write(output); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+	}
+
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf("compress output: %w", err)
+		}
+	}
+	return nil
+}
+
+func convertPayload(format string, payload []byte, opts convertOptions) ([]byte, error) {
+	var output []byte
+	var err error
+	switch format {
+	case "csv":
+		if opts.delimiter == ',' && len(opts.Renames) == 0 && opts.flattenSep == "" && opts.nullToken == "" {
+			return payload, nil
+		}
+		output, err = convertCSVDelimiter(payload, opts.delimiter, opts)
+		if err != nil {
+			return nil, fmt.Errorf("convert delimiter: %w", err)
+		}
+	case "tsv":
+		output, err = convertCSVDelimiter(payload, '\t', opts)
+		if err != nil {
+			return nil, fmt.Errorf("convert to TSV: %w", err)
+		}
+	case "json":
+		output, err = fuel.ToJSON(payload, opts.ConvertOptions)
+		if err != nil {
+			return nil, fmt.Errorf("convert to JSON: %w", err)
+		}
+	case "ndjson":
+		output, err = fuel.ToNDJSON(payload, opts.ConvertOptions)
+		if err != nil {
+			return nil, fmt.Errorf("convert to NDJSON: %w", err)
+		}
+	case "geojson":
+		output, err = convertCSVToGeoJSON(payload, opts)
+		if err != nil {
+			return nil, fmt.Errorf("convert to GeoJSON: %w", err)
+		}
+	case "xml":
+		output, err = convertCSVToXML(payload, opts)
+		if err != nil {
+			return nil, fmt.Errorf("convert to XML: %w", err)
+		}
+	case "yaml":
+		output, err = convertCSVToYAML(payload, opts)
+		if err != nil {
+			return nil, fmt.Errorf("convert to YAML: %w", err)
+		}
+	case "parquet":
+		output, err = convertCSVToParquet(payload, opts)
+		if err != nil {
+			return nil, fmt.Errorf("convert to Parquet: %w", err)
+		}
+	case "md":
+		output, err = convertCSVToMarkdown(payload, opts)
+		if err != nil {
+			return nil, fmt.Errorf("convert to Markdown: %w", err)
+		}
+	case "xlsx":
+		output, err = convertCSVToXLSX(payload, opts)
+		if err != nil {
+			return nil, fmt.Errorf("convert to XLSX: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+	return output, nil
+}
+
+// contentType returns the media type of the output written for format.
+func contentType(format string, compress bool) string {
+	if compress {
+		return "application/gzip"
+	}
+	switch format {
+	case "csv":
+		return "text/csv"
+	case "tsv":
+		return "text/tab-separated-values"
+	case "json":
+		return "application/json"
+	case "ndjson":
+		return "application/x-ndjson"
+	case "geojson":
+		return "application/geo+json"
+	case "xml":
+		return "application/xml"
+	case "yaml":
+		return "application/yaml"
+	case "parquet":
+		return "application/vnd.apache.parquet"
+	case "md":
+		return "text/markdown"
+	case "xlsx":
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// countingWriter counts the bytes passed through to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
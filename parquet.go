@@ -0,0 +1,201 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"fuelfinder-archive/pkg/fuel"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// convertCSVToParquet writes the rows as a Parquet file. Every column is
+// optional: nullable numeric fields become doubles, columns holding only
+// true/false become booleans and the rest are strings, unless the column is
+// declared otherwise in opts.Types. The payload is read
+// twice, once to infer the boolean columns and once to write, so rows are
+// streamed into the writer instead of being parsed into memory up front.
+func convertCSVToParquet(payload []byte, opts convertOptions) ([]byte, error) {
+	header, booleans, err := inferParquetColumns(payload)
+	if err != nil {
+		return nil, err
+	}
+	numeric := make([]bool, len(header))
+	for i, key := range header {
+		switch opts.ColumnType(key) {
+		case fuel.TypeNumeric:
+			numeric[i], booleans[i] = true, false
+		case fuel.TypeBool:
+			booleans[i] = true
+		case fuel.TypeString:
+			booleans[i] = false
+		}
+	}
+
+	names, err := underscoreColumns(opts.OutputNames(header))
+	if err != nil {
+		return nil, err
+	}
+
+	group := make(parquet.Group, len(header))
+	for i := range header {
+		var node parquet.Node
+		switch {
+		case numeric[i]:
+			node = parquet.Leaf(parquet.DoubleType)
+		case booleans[i]:
+			node = parquet.Leaf(parquet.BooleanType)
+		default:
+			node = parquet.String()
+		}
+		group[names[i]] = parquet.Optional(node)
+	}
+	schema := parquet.NewSchema("forecourts", group)
+
+	columnIndexes := make([]int, len(header))
+	for i, name := range names {
+		leaf, ok := schema.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("missing schema column %s", name)
+		}
+		columnIndexes[i] = leaf.ColumnIndex
+	}
+
+	var buf bytes.Buffer
+	writer := parquet.NewWriter(&buf, schema)
+
+	reader := fuel.NewCSVReader(payload)
+	if _, err := reader.Read(); err != nil {
+		return nil, err
+	}
+
+	row := make(parquet.Row, len(header))
+	var n int
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if fuel.BlankRecord(record) {
+			continue
+		}
+		n++
+		if len(record) != len(header) {
+			return nil, fmt.Errorf("output row %d: row has %d fields, expected %d", n, len(record), len(header))
+		}
+
+		for i, key := range header {
+			value, err := parquetValue(key, record[i], numeric[i], booleans[i], opts.ConvertOptions)
+			if err != nil {
+				return nil, fmt.Errorf("output row %d: %w", n, err)
+			}
+			definitionLevel := 1
+			if value.IsNull() {
+				definitionLevel = 0
+			}
+			row[columnIndexes[i]] = value.Level(0, definitionLevel, columnIndexes[i])
+		}
+		if _, err := writer.WriteRows([]parquet.Row{row}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// inferParquetColumns returns the header and, for each column, whether every
+// non-empty value is a boolean literal.
+func inferParquetColumns(payload []byte) ([]string, []bool, error) {
+	reader := fuel.NewCSVReader(payload)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(header) == 0 {
+		return nil, nil, errors.New("missing header row")
+	}
+
+	booleans := make([]bool, len(header))
+	seen := make([]bool, len(header))
+	for i := range booleans {
+		booleans[i] = true
+	}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if fuel.BlankRecord(record) {
+			continue
+		}
+		for i := range header {
+			value := fieldAt(record, i)
+			if value == "" {
+				continue
+			}
+			seen[i] = true
+			if value != "true" && value != "false" {
+				booleans[i] = false
+			}
+		}
+	}
+
+	for i := range booleans {
+		booleans[i] = booleans[i] && seen[i]
+	}
+	return header, booleans, nil
+}
+
+// parquetValue converts a CSV field to a Parquet value. Numeric fields go
+// through fuel.NormalizeValue and strings through fuel.NormalizeString, so
+// -price-unit, -round, -trim, -upper and -lower apply as in the other typed
+// formats.
+func parquetValue(key, raw string, numeric, boolean bool, opts fuel.ConvertOptions) (parquet.Value, error) {
+	if raw == "" && (boolean || numeric) {
+		return parquet.NullValue(), nil
+	}
+
+	switch {
+	case numeric:
+		value, err := fuel.NormalizeField(key, raw, opts)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		switch number := value.(type) {
+		case float64:
+			return parquet.DoubleValue(number), nil
+		case int64:
+			return parquet.DoubleValue(float64(number)), nil
+		}
+		return parquet.Value{}, fmt.Errorf("unexpected %T value", value)
+	case boolean:
+		value, err := parseBool(raw)
+		if err != nil {
+			var numErr *strconv.NumError
+			if errors.As(err, &numErr) {
+				err = numErr.Err
+			}
+			return parquet.Value{}, fmt.Errorf("parse %s %q: %w", key, raw, err)
+		}
+		return parquet.BooleanValue(value), nil
+	default:
+		return parquet.ByteArrayValue([]byte(fuel.NormalizeString(key, raw, opts))), nil
+	}
+}
@@ -0,0 +1,480 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package fuel
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ConvertOptions controls how CSV rows become records.
+type ConvertOptions struct {
+	// Integers types whole-number prices and coordinates as int64 rather
+	// than float64.
+	Integers bool
+	// Flat keeps dotted column names as top-level keys instead of nesting
+	// them into objects.
+	Flat bool
+	// Renames maps source column names to the names they are written
+	// under. Values keep the type of the source column.
+	Renames map[string]string
+	// Indent is the indent string for JSON; empty writes compact JSON.
+	Indent string
+	// PriceUnit converts fuel prices, published in pence per litre:
+	// PriceUnitPounds divides them by 100, while PriceUnitPence and the
+	// empty string leave them untouched.
+	PriceUnit string
+	// Trim removes leading and trailing whitespace from string values and
+	// collapses internal runs of whitespace to a single space.
+	Trim bool
+	// Upper and Lower list the columns whose string values are uppercased
+	// or lowercased, after any trimming.
+	Upper map[string]bool
+	Lower map[string]bool
+	// Types declares how columns are typed, overriding the built-in rules
+	// for prices, coordinates and true/false values. Values are TypeNumeric,
+	// TypeString or TypeBool.
+	Types map[string]string
+	// Nulls lists string columns whose empty values are written as null
+	// rather than an empty string.
+	Nulls map[string]bool
+	// RoundNumbers rounds numeric values other than coordinates to
+	// Decimals decimal places, after any PriceUnit conversion.
+	// RoundCoordinates does the same for latitude and longitude with
+	// CoordDecimals.
+	RoundNumbers     bool
+	Decimals         int
+	RoundCoordinates bool
+	CoordDecimals    int
+	// Wrap, if set, nests each JSON and NDJSON record under this key.
+	// Meta adds static fields alongside it.
+	Wrap string
+	Meta map[string]string
+	// SourceOrder writes JSON and NDJSON keys in the order of the header
+	// columns rather than alphabetically.
+	SourceOrder bool
+}
+
+// Units accepted by ConvertOptions.PriceUnit.
+const (
+	PriceUnitPence  = "pence"
+	PriceUnitPounds = "pounds"
+)
+
+// Column types accepted by ConvertOptions.Types.
+const (
+	TypeNumeric = "numeric"
+	TypeString  = "string"
+	TypeBool    = "bool"
+)
+
+// ColumnType returns the type declared for key in Types. Otherwise prices
+// and coordinates are TypeNumeric, and other columns return an empty string,
+// meaning each value is typed on its own.
+func (o ConvertOptions) ColumnType(key string) string {
+	if kind, ok := o.Types[key]; ok {
+		return kind
+	}
+	if IsNullableNumericField(key) {
+		return TypeNumeric
+	}
+	return ""
+}
+
+// WrapRecord nests record under o.Wrap next to the fields in o.Meta, or
+// returns it unchanged when Wrap is empty. With SourceOrder the wrap key
+// comes first, followed by the fields in alphabetical order.
+func (o ConvertOptions) WrapRecord(record any) any {
+	if o.Wrap == "" {
+		return record
+	}
+	if o.SourceOrder {
+		wrapped := NewObject()
+		wrapped.Set(o.Wrap, record)
+		for _, key := range slices.Sorted(maps.Keys(o.Meta)) {
+			wrapped.Set(key, o.Meta[key])
+		}
+		return wrapped
+	}
+	wrapped := make(map[string]any, len(o.Meta)+1)
+	for key, value := range o.Meta {
+		wrapped[key] = value
+	}
+	wrapped[o.Wrap] = record
+	return wrapped
+}
+
+// OutputName returns the name the column key is written under.
+func (o ConvertOptions) OutputName(key string) string {
+	if name, ok := o.Renames[key]; ok {
+		return name
+	}
+	return key
+}
+
+// OutputNames returns the names the header columns are written under.
+func (o ConvertOptions) OutputNames(header []string) []string {
+	names := make([]string, len(header))
+	for i, key := range header {
+		names[i] = o.OutputName(key)
+	}
+	return names
+}
+
+// ToJSON converts the payload to a JSON array of records.
+func ToJSON(payload []byte, opts ConvertOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := StreamJSON(&buf, payload, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// StreamJSON writes the rows to w as a JSON array one record at a time,
+// so only a single record is held in memory alongside the input. An empty
+// opts.Indent writes compact JSON.
+func StreamJSON(w io.Writer, payload []byte, opts ConvertOptions) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent(opts.Indent, opts.Indent)
+	separator := ""
+	if opts.Indent != "" {
+		separator = "\n" + opts.Indent
+	}
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	count := 0
+	err := eachRecord(payload, opts, func(record any) error {
+		buf.Reset()
+		if count > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(separator)
+		if err := encoder.Encode(opts.WrapRecord(record)); err != nil {
+			return err
+		}
+		buf.Truncate(buf.Len() - 1)
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	closing := "]"
+	if count > 0 && opts.Indent != "" {
+		closing = "\n]"
+	}
+	_, err = io.WriteString(w, closing)
+	return err
+}
+
+// ToNDJSON converts the payload to newline-delimited JSON, one compact
+// record per line.
+func ToNDJSON(payload []byte, opts ConvertOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	err := eachRecord(payload, opts, func(record any) error {
+		line, err := json.Marshal(opts.WrapRecord(record))
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// eachRecord calls fn with every row of the payload as a record: a map, or
+// an *Object with opts.SourceOrder. Only one record is held at a time.
+// Errors carry the output row of the failing record, as in ParseRecords.
+func eachRecord(payload []byte, opts ConvertOptions, fn func(record any) error) error {
+	reader := NewCSVReader(payload)
+
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	if len(header) == 0 {
+		return errors.New("missing header row")
+	}
+
+	var n int
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if BlankRecord(row) {
+			continue
+		}
+		n++
+		var record any
+		if opts.SourceOrder {
+			record, err = BuildOrderedRecord(header, row, opts)
+		} else {
+			record, err = BuildRecord(header, row, opts)
+		}
+		if err != nil {
+			return fmt.Errorf("output row %d: %w", n, err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+// ParseRecords converts every row of the payload into a record. Errors
+// carry the position of the failing record among the rows written, counting
+// from 1. Filtering and sorting may have moved it from its line in the
+// source feed.
+func ParseRecords(payload []byte, opts ConvertOptions) ([]map[string]any, error) {
+	reader := NewCSVReader(payload)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if len(header) == 0 {
+		return nil, errors.New("missing header row")
+	}
+
+	records := []map[string]any{}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if BlankRecord(row) {
+			continue
+		}
+		entry, err := BuildRecord(header, row, opts)
+		if err != nil {
+			return nil, fmt.Errorf("output row %d: %w", len(records)+1, err)
+		}
+		records = append(records, entry)
+	}
+
+	return records, nil
+}
+
+// BuildRecord types each value of row and nests it under its column name,
+// split on dots unless opts.Flat is set.
+func BuildRecord(header, row []string, opts ConvertOptions) (map[string]any, error) {
+	if len(row) != len(header) {
+		return nil, fmt.Errorf("row has %d fields, expected %d", len(row), len(header))
+	}
+
+	entry := make(map[string]any, len(header))
+	for i, key := range header {
+		value, err := NormalizeField(key, row[i], opts)
+		if err != nil {
+			return nil, err
+		}
+		name := opts.OutputName(key)
+		if opts.Flat {
+			entry[name] = value
+			continue
+		}
+		if err := SetNestedValue(entry, strings.Split(name, "."), value); err != nil {
+			return nil, fmt.Errorf("set %s: %w", key, err)
+		}
+	}
+	return entry, nil
+}
+
+// NormalizeField is NormalizeValue with the column and raw value in the
+// error, the form every output format reports a bad value in.
+func NormalizeField(key, raw string, opts ConvertOptions) (any, error) {
+	value, err := NormalizeValue(key, raw, opts)
+	if err != nil {
+		// The raw value is quoted here, so drop strconv's own copy.
+		var numErr *strconv.NumError
+		if errors.As(err, &numErr) {
+			err = numErr.Err
+		}
+		return nil, fmt.Errorf("parse %s %q: %w", key, raw, err)
+	}
+	return value, nil
+}
+
+// NormalizeValue types a raw CSV value: prices and coordinates become
+// numbers (nil when empty), true and false become booleans and everything
+// else stays a string. Columns listed in opts.Types are typed as declared,
+// with numeric and bool columns nil when empty, as are the string columns
+// in opts.Nulls.
+func NormalizeValue(key, raw string, opts ConvertOptions) (any, error) {
+	kind := opts.ColumnType(key)
+	if raw == "" {
+		if kind == TypeNumeric || kind == TypeBool || opts.Nulls[key] {
+			return nil, nil
+		}
+		return "", nil
+	}
+
+	switch kind {
+	case TypeNumeric:
+		value, err := parseNumber(key, raw, opts)
+		if err != nil {
+			return nil, err
+		}
+		if number, ok := value.(float64); ok {
+			if places, ok := opts.decimals(key); ok {
+				value = roundTo(number, places)
+			}
+		}
+		return value, nil
+	case TypeBool:
+		value, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	case TypeString:
+		return NormalizeString(key, raw, opts), nil
+	}
+
+	if raw == "true" || raw == "false" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+	return NormalizeString(key, raw, opts), nil
+}
+
+// parseNumber parses a numeric value, converting prices to pounds when
+// asked and keeping whole numbers as int64 with opts.Integers.
+func parseNumber(key, raw string, opts ConvertOptions) (any, error) {
+	if opts.PriceUnit == PriceUnitPounds && strings.HasPrefix(key, PricePrefix) {
+		return parsePounds(raw, opts)
+	}
+	if opts.Integers && !strings.ContainsAny(raw, ".eE") {
+		if value, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return value, nil
+		}
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// decimals returns the decimal places the numeric column key is rounded
+// to, and whether it is rounded at all.
+func (o ConvertOptions) decimals(key string) (int, bool) {
+	if key == "forecourts.location.latitude" || key == "forecourts.location.longitude" {
+		return o.CoordDecimals, o.RoundCoordinates
+	}
+	return o.Decimals, o.RoundNumbers
+}
+
+// roundTo rounds value to places decimal places, halves away from zero.
+func roundTo(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
+
+// NormalizeString applies the Trim, Upper and Lower options to a string
+// value.
+func NormalizeString(key, raw string, opts ConvertOptions) string {
+	if opts.Trim {
+		raw = strings.Join(strings.Fields(raw), " ")
+	}
+	switch {
+	case opts.Upper[key]:
+		raw = strings.ToUpper(raw)
+	case opts.Lower[key]:
+		raw = strings.ToLower(raw)
+	}
+	return raw
+}
+
+// parsePounds converts a price in pence to pounds. Shifting the exponent
+// rather than dividing keeps 125.9 as 1.259 instead of 1.2590000000000001.
+func parsePounds(raw string, opts ConvertOptions) (any, error) {
+	var value float64
+	var err error
+	if strings.ContainsAny(raw, "eE") {
+		value, err = strconv.ParseFloat(raw, 64)
+		value /= 100
+	} else {
+		value, err = strconv.ParseFloat(raw+"e-2", 64)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.Integers && value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+		return int64(value), nil
+	}
+	return value, nil
+}
+
+// IsNullableNumericField reports whether the column key holds a price or
+// coordinate, typed as a number and null when empty.
+func IsNullableNumericField(key string) bool {
+	if key == "forecourts.location.latitude" || key == "forecourts.location.longitude" {
+		return true
+	}
+	return strings.HasPrefix(key, PricePrefix)
+}
+
+// SetNestedValue stores value in root under path, creating intermediate
+// objects as needed.
+func SetNestedValue(root map[string]any, path []string, value any) error {
+	if len(path) == 0 {
+		return errors.New("empty key path")
+	}
+
+	current := root
+	for i := 0; i < len(path)-1; i++ {
+		segment := path[i]
+		if segment == "" {
+			return errors.New("empty key segment")
+		}
+		if next, ok := current[segment]; ok {
+			nested, ok := next.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s is not an object", strings.Join(path[:i+1], "."))
+			}
+			current = nested
+			continue
+		}
+		child := make(map[string]any)
+		current[segment] = child
+		current = child
+	}
+
+	leaf := path[len(path)-1]
+	if leaf == "" {
+		return errors.New("empty key segment")
+	}
+	current[leaf] = value
+	return nil
+}
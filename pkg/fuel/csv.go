@@ -0,0 +1,108 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package fuel
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var utf8BOM = []byte("\ufeff")
+
+// NewCSVReader returns a permissive reader over payload, skipping a leading
+// UTF-8 byte order mark so it does not leak into the first column name.
+func NewCSVReader(payload []byte) *csv.Reader {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(payload, utf8BOM)))
+	reader.FieldsPerRecord = -1
+	return reader
+}
+
+// Validate parses the whole payload and returns the number of data rows.
+// checkHeader, if not nil, is called with the header row and can reject it.
+func Validate(payload []byte, checkHeader func(header []string) error) (int, error) {
+	reader := NewCSVReader(payload)
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if checkHeader != nil {
+		if err := checkHeader(header); err != nil {
+			return 0, fmt.Errorf("schema mismatch: %w", err)
+		}
+	}
+
+	rows := 0
+	for {
+		row, err := reader.Read()
+		if err == nil {
+			if !BlankRecord(row) {
+				rows++
+			}
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		return 0, err
+	}
+}
+
+// BlankRecord reports whether every field of record is empty or whitespace,
+// such as a line of bare commas or spaces at the end of some feed variants.
+// Such records are skipped instead of being treated as malformed rows.
+func BlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadCSV returns the header and every non-blank row of payload.
+func ReadCSV(payload []byte) ([]string, [][]string, error) {
+	reader := NewCSVReader(payload)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows [][]string
+	for {
+		row, err := reader.Read()
+		if err == nil {
+			if !BlankRecord(row) {
+				rows = append(rows, row)
+			}
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return header, rows, nil
+		}
+		return nil, nil, err
+	}
+}
+
+// WriteCSV encodes header and rows as comma-separated values.
+func WriteCSV(header []string, rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package fuel
+
+import (
+	"bytes"
+	"testing"
+)
+
+const bomPayload = "\ufeffforecourt_update_timestamp,forecourts.node_id,forecourts.fuel_price.E10\n" +
+	"2026-03-01T08:00:00Z,abc123,129.9\n"
+
+func TestValidateSkipsBOM(t *testing.T) {
+	var first string
+	rows, err := Validate([]byte(bomPayload), func(header []string) error {
+		first = header[0]
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if rows != 1 {
+		t.Errorf("rows = %d, want 1", rows)
+	}
+	if first != "forecourt_update_timestamp" {
+		t.Errorf("first column = %q, want %q", first, "forecourt_update_timestamp")
+	}
+}
+
+func TestToJSONSkipsBOM(t *testing.T) {
+	for _, opts := range []ConvertOptions{{}, {SourceOrder: true}, {Flat: true}} {
+		out, err := ToJSON([]byte(bomPayload), opts)
+		if err != nil {
+			t.Fatalf("ToJSON(%+v): %v", opts, err)
+		}
+		if want := `[{"forecourt_update_timestamp":`; !bytes.HasPrefix(out, []byte(want)) {
+			t.Errorf("ToJSON(%+v) = %s, want prefix %s", opts, out, want)
+		}
+	}
+}
+
+// blankTailPayload uses CRLF line endings and ends in the blank lines some
+// feed variants append: bare commas, spaces and an empty line.
+const blankTailPayload = "forecourts.node_id,forecourts.fuel_price.E10\r\n" +
+	"abc123,129.9\r\n" +
+	"def456,131.9\r\n" +
+	",\r\n" +
+	" , \r\n" +
+	"\r\n"
+
+func TestValidateSkipsBlankRows(t *testing.T) {
+	rows, err := Validate([]byte(blankTailPayload), nil)
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if rows != 2 {
+		t.Errorf("rows = %d, want 2", rows)
+	}
+}
+
+func TestParseRecordsSkipsBlankRows(t *testing.T) {
+	records, err := ParseRecords([]byte(blankTailPayload), ConvertOptions{Flat: true})
+	if err != nil {
+		t.Fatalf("ParseRecords: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want 2", len(records))
+	}
+	if got := records[1]["forecourts.fuel_price.E10"]; got != 131.9 {
+		t.Errorf("last price = %v, want 131.9", got)
+	}
+}
+
+func TestStreamJSONSkipsBlankRows(t *testing.T) {
+	var out bytes.Buffer
+	if err := StreamJSON(&out, []byte(blankTailPayload), ConvertOptions{Flat: true}); err != nil {
+		t.Fatalf("StreamJSON: %v", err)
+	}
+	want := `[{"forecourts.fuel_price.E10":129.9,"forecourts.node_id":"abc123"},` +
+		`{"forecourts.fuel_price.E10":131.9,"forecourts.node_id":"def456"}]`
+	if got := string(bytes.TrimSpace(out.Bytes())); got != want {
+		t.Errorf("StreamJSON = %s, want %s", got, want)
+	}
+}
+
+func TestConversionErrorsNameOutputRow(t *testing.T) {
+	payload := []byte("forecourts.node_id,forecourts.fuel_price.E10\n" +
+		"abc123,129.9\n" +
+		",\n" +
+		"def456,n/a\n")
+	want := `output row 2: parse forecourts.fuel_price.E10 "n/a": invalid syntax`
+
+	if _, err := ParseRecords(payload, ConvertOptions{}); err == nil || err.Error() != want {
+		t.Errorf("ParseRecords error = %v, want %s", err, want)
+	}
+	if _, err := ToNDJSON(payload, ConvertOptions{}); err == nil || err.Error() != want {
+		t.Errorf("ToNDJSON error = %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package fuel
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrNotModified is returned by Fetch when upstream answers a conditional
+// request with 304 Not Modified.
+var ErrNotModified = errors.New("not modified")
+
+// ErrTooLarge is returned when a response body is larger than
+// FetchOptions.MaxBytes.
+var ErrTooLarge = errors.New("response exceeded max size")
+
+// CacheMeta holds the validators from a successful response. Passing them
+// back in FetchOptions.Cache issues a conditional GET.
+type CacheMeta struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// Empty reports whether there are no validators to send.
+func (m CacheMeta) Empty() bool {
+	return m.ETag == "" && m.LastModified == ""
+}
+
+// FetchOptions configures Fetch and FetchURL.
+type FetchOptions struct {
+	// Source is the URL of the CSV, usually DefaultURL.
+	Source string
+	// ProxyTemplate, if set, is a comma-separated list of proxy templates,
+	// each adding a fallback target tried after Source in order. See
+	// ProxyURL for the placeholders they may contain.
+	ProxyTemplate string
+	// Retries is the number of retries for transient errors per target,
+	// starting at RetryBase and doubling with jitter.
+	Retries   int
+	RetryBase time.Duration
+	// TargetTimeout bounds each attempt; zero relies on the client timeout.
+	TargetTimeout time.Duration
+	// RateLimitRetries is the number of retries for 429 responses per
+	// target, counted apart from Retries.
+	RateLimitRetries int
+	// MaxRetryAfter caps the wait honoured for a 429 response; zero means
+	// no cap.
+	MaxRetryAfter time.Duration
+	// MaxBytes caps the size of a response body after decompression;
+	// zero means no cap.
+	MaxBytes int64
+	// Credentials are sent as basic auth to Source only, never to the
+	// proxy target.
+	Credentials *url.Userinfo
+	// UserAgent is sent as the User-Agent header; empty omits it.
+	UserAgent string
+	// Headers are added to every request, replacing defaults of the same
+	// name.
+	Headers http.Header
+	// Cache holds validators from a previous response for a conditional
+	// GET.
+	Cache CacheMeta
+	// Trace, if set, receives a line per attempt breaking down the time
+	// spent on DNS, connecting, the TLS handshake, the first response byte
+	// and the whole request.
+	Trace io.Writer
+}
+
+// Result is a successfully fetched payload.
+type Result struct {
+	Payload []byte
+	// Source is the target the payload came from.
+	Source      string
+	Status      int
+	ContentType string
+	Cache       CacheMeta
+}
+
+// Fetch downloads the CSV from opts.Source, falling back to each proxy
+// target in turn when the source fails or answers with something other than CSV
+// (ErrNotCSV). It returns ErrNotModified if upstream reports the cached
+// copy is current.
+func Fetch(ctx context.Context, client *http.Client, opts FetchOptions) (*Result, error) {
+	var lastErr error
+	for _, target := range Targets(opts.Source, opts.ProxyTemplate) {
+		// Credentials belong to the source; never send them to a proxy.
+		targetOpts := opts
+		if target != opts.Source {
+			targetOpts.Credentials = nil
+		}
+		result, err := FetchURL(ctx, client, target, targetOpts)
+		if errors.Is(err, ErrNotModified) || ctx.Err() != nil {
+			return nil, err
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(result.Payload) == 0 {
+			lastErr = errors.New("received empty response")
+			continue
+		}
+		if err := sniffCSV(result.ContentType, result.Payload); err != nil {
+			lastErr = err
+			continue
+		}
+		return result, nil
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, errors.New("failed to fetch fuel data")
+}
+
+// Targets returns the URLs Fetch tries in order: the source, then the
+// source rewritten through each of the comma-separated proxyTemplates.
+// Empty templates are skipped.
+func Targets(source, proxyTemplates string) []string {
+	targets := []string{source}
+	for _, template := range strings.Split(proxyTemplates, ",") {
+		template = strings.TrimSpace(template)
+		if template != "" {
+			targets = append(targets, ProxyURL(template, source))
+		}
+	}
+	return targets
+}
+
+// proxyPlaceholders are the placeholders ProxyURL substitutes.
+var proxyPlaceholders = []string{"{url}", "{url_raw}", "{url_b64}", "{host}", "{path}"}
+
+// ProxyURL rewrites target through template, replacing these placeholders:
+//
+//   - {url}: the query-escaped target
+//   - {url_raw}: the target as is
+//   - {url_b64}: the target in unpadded base64url
+//   - {host}: the host of the target, with any port
+//   - {path}: the path of the target, starting with a slash, without the
+//     query
+//
+// A template without any placeholder has the target appended to it.
+func ProxyURL(template, target string) string {
+	if !slices.ContainsFunc(proxyPlaceholders, func(placeholder string) bool {
+		return strings.Contains(template, placeholder)
+	}) {
+		return template + target
+	}
+
+	var host, path string
+	if parsed, err := url.Parse(target); err == nil {
+		host, path = parsed.Host, parsed.EscapedPath()
+	}
+	if path == "" {
+		path = "/"
+	}
+	return strings.NewReplacer(
+		"{url}", url.QueryEscape(target),
+		"{url_raw}", target,
+		"{url_b64}", base64.RawURLEncoding.EncodeToString([]byte(target)),
+		"{host}", host,
+		"{path}", path,
+	).Replace(template)
+}
+
+func fetchOnce(ctx context.Context, client *http.Client, target string, opts FetchOptions) (*Result, error) {
+	// The client timeout still bounds every request; a per-target timeout
+	// stops a hung source from using it all up before the proxy is tried.
+	if opts.TargetTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.TargetTimeout)
+		defer cancel()
+	}
+	if opts.Trace != nil {
+		var timings *requestTimings
+		ctx, timings = withTimings(ctx)
+		defer timings.write(opts.Trace, target)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	// An empty User-Agent stops net/http from sending its default one.
+	req.Header.Set("User-Agent", opts.UserAgent)
+	req.Header.Set("Accept", "text/csv,application/octet-stream;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-GB,en;q=0.9")
+	req.Header.Set("Referer", "https://www.gov.uk/guidance/access-fuel-price-data")
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("Pragma", "no-cache")
+	// Asking for gzip explicitly turns off the transport's transparent
+	// decompression, so the body is decoded below. This works the same with
+	// any client, including one that disables compression.
+	req.Header.Set("Accept-Encoding", "gzip")
+	for name, values := range opts.Headers {
+		req.Header[name] = values
+	}
+	if opts.Credentials != nil {
+		password, _ := opts.Credentials.Password()
+		req.SetBasicAuth(opts.Credentials.Username(), password)
+	}
+	if opts.Cache.ETag != "" {
+		req.Header.Set("If-None-Match", opts.Cache.ETag)
+	}
+	if opts.Cache.LastModified != "" {
+		req.Header.Set("If-Modified-Since", opts.Cache.LastModified)
+	}
+
+	slog.Info("fetching fuel data", "url", target)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch fuel data: %w", err)
+	}
+	defer resp.Body.Close()
+	slog.Info("received response", "url", target, "status", resp.Status)
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &StatusError{
+			Code:       resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("read gzip response: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	if opts.MaxBytes > 0 {
+		// Read one byte past the cap to tell a body of exactly MaxBytes
+		// from a larger one.
+		body = io.LimitReader(body, opts.MaxBytes+1)
+	}
+
+	payload, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if opts.MaxBytes > 0 && int64(len(payload)) > opts.MaxBytes {
+		return nil, fmt.Errorf("%w of %d bytes", ErrTooLarge, opts.MaxBytes)
+	}
+	slog.Debug("read response body", "url", target, "bytes", len(payload), "encoding", resp.Header.Get("Content-Encoding"))
+
+	return &Result{
+		Payload:     payload,
+		Source:      target,
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Cache: CacheMeta{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		},
+	}, nil
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package fuel
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTargets(t *testing.T) {
+	const source = "https://example.com/data.csv"
+	tests := []struct {
+		name      string
+		templates string
+		want      []string
+	}{
+		{"no templates", "", []string{source}},
+		{"only separators", " , ", []string{source}},
+		{"one template", "https://proxy.example/?u={url}", []string{
+			source,
+			"https://proxy.example/?u=https%3A%2F%2Fexample.com%2Fdata.csv",
+		}},
+		{"two templates", "https://one.example/{host}{path}, https://two.example/fetch?url=", []string{
+			source,
+			"https://one.example/example.com/data.csv",
+			"https://two.example/fetch?url=https://example.com/data.csv",
+		}},
+		{"empty entry between templates", "https://one.example{path},,https://two.example{path}", []string{
+			source,
+			"https://one.example/data.csv",
+			"https://two.example/data.csv",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Targets(source, tt.templates); !slices.Equal(got, tt.want) {
+				t.Errorf("Targets(%q) = %q, want %q", tt.templates, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package fuel fetches the UK Fuel Finder price CSV and converts it into
+// typed records. It is the library behind the fuelfinder-archive command.
+//
+// A minimal program fetches the latest feed and prints it as JSON:
+//
+//	result, err := fuel.Fetch(ctx, http.DefaultClient, fuel.FetchOptions{
+//		Source:    fuel.DefaultURL,
+//		UserAgent: "my-service/1.0",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	return fuel.StreamJSON(os.Stdout, result.Payload, fuel.ConvertOptions{Integers: true})
+package fuel
+
+const (
+	// DefaultURL is the Fuel Finder endpoint serving the latest prices.
+	DefaultURL = "https://www.fuel-finder.service.gov.uk/internal/v1.0.2/csv/get-latest-fuel-prices-csv"
+
+	// DefaultUserAgent is the browser User-Agent the command sends unless
+	// told otherwise.
+	DefaultUserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+	// PricePrefix starts the name of every fuel price column, followed by
+	// the fuel code such as E10 or B7S.
+	PricePrefix = "forecourts.fuel_price."
+)
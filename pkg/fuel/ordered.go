@@ -0,0 +1,126 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package fuel
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Object is a JSON object that keeps its keys in the order they were first
+// set, unlike a map, which encoding/json writes in alphabetical order.
+type Object struct {
+	keys   []string
+	values map[string]any
+}
+
+// NewObject returns an empty Object.
+func NewObject() *Object {
+	return &Object{values: make(map[string]any)}
+}
+
+// Set stores value under key, keeping the position of an existing key.
+func (o *Object) Set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// Get returns the value stored under key.
+func (o *Object) Get(key string) (any, bool) {
+	value, ok := o.values[key]
+	return value, ok
+}
+
+// Keys returns the keys in order.
+func (o *Object) Keys() []string {
+	return o.keys
+}
+
+// MarshalJSON writes the keys in order.
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		value, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// BuildOrderedRecord is BuildRecord keeping the columns in header order,
+// each nested object placed where its first column appears.
+func BuildOrderedRecord(header, row []string, opts ConvertOptions) (*Object, error) {
+	if len(row) != len(header) {
+		return nil, fmt.Errorf("row has %d fields, expected %d", len(row), len(header))
+	}
+
+	entry := NewObject()
+	for i, key := range header {
+		value, err := NormalizeField(key, row[i], opts)
+		if err != nil {
+			return nil, err
+		}
+		name := opts.OutputName(key)
+		if opts.Flat {
+			entry.Set(name, value)
+			continue
+		}
+		if err := setOrderedValue(entry, strings.Split(name, "."), value); err != nil {
+			return nil, fmt.Errorf("set %s: %w", key, err)
+		}
+	}
+	return entry, nil
+}
+
+// setOrderedValue is SetNestedValue for an Object.
+func setOrderedValue(root *Object, path []string, value any) error {
+	if len(path) == 0 {
+		return errors.New("empty key path")
+	}
+
+	current := root
+	for i := 0; i < len(path)-1; i++ {
+		segment := path[i]
+		if segment == "" {
+			return errors.New("empty key segment")
+		}
+		if next, ok := current.Get(segment); ok {
+			nested, ok := next.(*Object)
+			if !ok {
+				return fmt.Errorf("%s is not an object", strings.Join(path[:i+1], "."))
+			}
+			current = nested
+			continue
+		}
+		child := NewObject()
+		current.Set(segment, child)
+		current = child
+	}
+
+	leaf := path[len(path)-1]
+	if leaf == "" {
+		return errors.New("empty key segment")
+	}
+	current.Set(leaf, value)
+	return nil
+}
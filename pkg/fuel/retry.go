@@ -0,0 +1,135 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package fuel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// StatusError reports a non-200 response from upstream. RetryAfter holds
+// the parsed Retry-After header of a 429 response, if any.
+type StatusError struct {
+	Code       int
+	Status     string
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return "unexpected status: " + e.Status
+}
+
+// FetchURL fetches a single target, retrying transient failures with
+// exponential backoff and jitter up to opts.Retries times. Rate-limited
+// responses are retried up to opts.RateLimitRetries times on a separate
+// count, waiting for the server's Retry-After instead, capped at
+// opts.MaxRetryAfter when it is set.
+func FetchURL(ctx context.Context, client *http.Client, target string, opts FetchOptions) (*Result, error) {
+	var retried, rateLimited int
+	for {
+		result, err := fetchOnce(ctx, client, target, opts)
+		if err == nil || ctx.Err() != nil {
+			return result, err
+		}
+
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.Code == http.StatusTooManyRequests {
+			if rateLimited >= opts.RateLimitRetries {
+				return result, err
+			}
+			delay := statusErr.RetryAfter
+			if delay <= 0 {
+				delay = backoffDelay(opts.RetryBase, rateLimited)
+			}
+			if opts.MaxRetryAfter > 0 {
+				delay = min(delay, opts.MaxRetryAfter)
+			}
+			rateLimited++
+			slog.Warn("rate limited, retrying fetch", "url", target, "attempt", rateLimited, "retries", opts.RateLimitRetries, "delay", delay)
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !isTransientError(err) || retried >= opts.Retries {
+			return result, err
+		}
+
+		delay := backoffDelay(opts.RetryBase, retried)
+		retried++
+		slog.Warn("retrying fetch", "url", target, "attempt", retried, "retries", opts.Retries, "delay", delay, "error", err)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleepContext waits for delay, returning early with the context's error
+// if it is cancelled first.
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given either as a number of
+// seconds or as an HTTP date. It returns zero if the header is missing or
+// invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
+
+// isTransientError reports whether err is worth retrying: 5xx responses,
+// timeouts and dropped connections.
+func isTransientError(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code >= http.StatusInternalServerError
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// backoffDelay doubles base for every attempt and picks a random delay in
+// the upper half of that window.
+func backoffDelay(base time.Duration, attempt int) time.Duration {
+	delay := base << attempt
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
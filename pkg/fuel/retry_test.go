@@ -0,0 +1,135 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package fuel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"empty", "", 0},
+		{"seconds", "5", 5 * time.Second},
+		{"padded seconds", " 2 ", 2 * time.Second},
+		{"negative seconds", "-3", 0},
+		{"http date", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{"past http date", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"invalid", "soon", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// rateLimitedServer answers the first request with a 429 carrying
+// retryAfter and every later one with a small CSV.
+func rateLimitedServer(t *testing.T, retryAfter func() string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", retryAfter())
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("forecourts.node_id,forecourts.fuel_price.E10\n1,129.9\n"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestFetchURLRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter func() string
+		maxWait    time.Duration
+		atLeast    time.Duration
+		atMost     time.Duration
+	}{
+		{
+			name:       "seconds",
+			retryAfter: func() string { return "1" },
+			atLeast:    time.Second,
+			atMost:     5 * time.Second,
+		},
+		{
+			name: "http date",
+			retryAfter: func() string {
+				return time.Now().Add(2 * time.Second).UTC().Format(http.TimeFormat)
+			},
+			// The date has second precision, so the wait is anywhere
+			// between one and two seconds.
+			atLeast: 500 * time.Millisecond,
+			atMost:  5 * time.Second,
+		},
+		{
+			name:       "capped",
+			retryAfter: func() string { return "3600" },
+			maxWait:    50 * time.Millisecond,
+			atLeast:    50 * time.Millisecond,
+			atMost:     5 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := rateLimitedServer(t, tt.retryAfter)
+			opts := FetchOptions{
+				RateLimitRetries: 1,
+				RetryBase:        time.Hour,
+				MaxRetryAfter:    tt.maxWait,
+			}
+
+			start := time.Now()
+			result, err := FetchURL(context.Background(), server.Client(), server.URL, opts)
+			elapsed := time.Since(start)
+			if err != nil {
+				t.Fatalf("FetchURL: %v", err)
+			}
+			if result.Status != http.StatusOK {
+				t.Errorf("status = %d, want %d", result.Status, http.StatusOK)
+			}
+			if got := requests.Load(); got != 2 {
+				t.Errorf("requests = %d, want 2", got)
+			}
+			if elapsed < tt.atLeast || elapsed > tt.atMost {
+				t.Errorf("waited %v, want between %v and %v", elapsed, tt.atLeast, tt.atMost)
+			}
+		})
+	}
+}
+
+// Retries covers transient failures only, so it does not let a 429 through
+// when RateLimitRetries is zero.
+func TestFetchURLRateLimitedWithoutRetries(t *testing.T) {
+	server, requests := rateLimitedServer(t, func() string { return "1" })
+
+	_, err := FetchURL(context.Background(), server.Client(), server.URL, FetchOptions{Retries: 3})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("err = %v, want *StatusError", err)
+	}
+	if statusErr.Code != http.StatusTooManyRequests || statusErr.RetryAfter != time.Second {
+		t.Errorf("err = %+v, want 429 with a 1s Retry-After", statusErr)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package fuel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// ErrNotCSV is returned when upstream answers 200 with something other
+// than CSV, such as an HTML maintenance page or a JSON error document.
+var ErrNotCSV = errors.New("response is not CSV")
+
+// sniffCSV rejects payloads whose Content-Type or first bytes show they are
+// HTML or JSON, or whose first line has no commas to separate columns.
+func sniffCSV(contentType string, payload []byte) error {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/html", "application/xhtml+xml", "application/json", "application/problem+json":
+		return fmt.Errorf("%w: content type %s: %s", ErrNotCSV, mediaType, snippet(payload))
+	}
+
+	body := bytes.TrimLeft(bytes.TrimPrefix(payload, utf8BOM), " \t\r\n")
+	if len(body) == 0 {
+		return nil
+	}
+	switch body[0] {
+	case '<':
+		return fmt.Errorf("%w: looks like HTML: %s", ErrNotCSV, snippet(payload))
+	case '{', '[':
+		return fmt.Errorf("%w: looks like JSON: %s", ErrNotCSV, snippet(payload))
+	}
+
+	firstLine, _, _ := bytes.Cut(body, []byte("\n"))
+	if !bytes.Contains(firstLine, []byte(",")) {
+		return fmt.Errorf("%w: header has no columns: %s", ErrNotCSV, snippet(payload))
+	}
+	return nil
+}
+
+// snippet returns the start of payload on one line for error messages.
+func snippet(payload []byte) string {
+	const limit = 80
+	text := strings.Join(strings.Fields(string(bytes.TrimPrefix(payload, utf8BOM))), " ")
+	if len(text) > limit {
+		text = text[:limit] + "..."
+	}
+	return fmt.Sprintf("%q", text)
+}
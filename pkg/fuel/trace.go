@@ -0,0 +1,67 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package fuel
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http/httptrace"
+	"time"
+)
+
+// requestTimings records when each phase of a request started and ended.
+// Phases the request skipped, such as DNS for an IP address or TLS for
+// plain HTTP and reused connections, stay zero.
+type requestTimings struct {
+	start                 time.Time
+	dnsStart, dnsDone     time.Time
+	connectStart, connect time.Time
+	tlsStart, tlsDone     time.Time
+	firstByte             time.Time
+	reused                bool
+}
+
+// withTimings attaches an httptrace.ClientTrace to ctx that fills in the
+// returned timings.
+func withTimings(ctx context.Context) (context.Context, *requestTimings) {
+	t := &requestTimings{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.dnsStart = time.Now() },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.dnsDone = time.Now() },
+		ConnectStart: func(string, string) {
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone:          func(string, string, error) { t.connect = time.Now() },
+		TLSHandshakeStart:    func() { t.tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.tlsDone = time.Now() },
+		GotConn:              func(info httptrace.GotConnInfo) { t.reused = info.Reused },
+		GotFirstResponseByte: func() { t.firstByte = time.Now() },
+	}
+	return httptrace.WithClientTrace(ctx, trace), t
+}
+
+// write prints one line for target with the duration of each phase and the
+// total time from sending the request to reading the whole body.
+func (t *requestTimings) write(w io.Writer, target string) {
+	phase := func(start, end time.Time) time.Duration {
+		if start.IsZero() || end.IsZero() {
+			return 0
+		}
+		return end.Sub(start).Round(time.Microsecond)
+	}
+	fmt.Fprintf(w, "trace %s: dns %v, connect %v, tls %v, ttfb %v, total %v, reused %t\n",
+		target,
+		phase(t.dnsStart, t.dnsDone),
+		phase(t.connectStart, t.connect),
+		phase(t.tlsStart, t.tlsDone),
+		phase(t.start, t.firstByte),
+		time.Since(t.start).Round(time.Microsecond),
+		t.reused,
+	)
+}
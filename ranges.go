@@ -0,0 +1,96 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// priceRange is the band of fuel prices, in pence per litre, accepted by
+// -validate-ranges.
+type priceRange struct {
+	min float64
+	max float64
+}
+
+// rangeViolation describes a value outside its accepted range. row is the
+// 1-based data row, not counting the header.
+type rangeViolation struct {
+	row    int
+	siteID string
+	column string
+	value  float64
+	min    float64
+	max    float64
+}
+
+func (v rangeViolation) String() string {
+	return fmt.Sprintf("row %d (%s): %s %v outside [%v, %v]", v.row, v.siteID, v.column, v.value, v.min, v.max)
+}
+
+// checkRanges drops rows whose coordinates or fuel prices are out of range.
+// Empty and non-numeric values are left for the converters to judge. It
+// returns the re-encoded CSV, the number of rows kept and the first
+// violation found in each dropped row.
+func checkRanges(payload []byte, prices priceRange) ([]byte, int, []rangeViolation, error) {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	type bound struct {
+		index    int
+		min, max float64
+	}
+	var bounds []bound
+	siteIndex := -1
+	for i, column := range header {
+		switch {
+		case column == siteIDColumn:
+			siteIndex = i
+		case column == latitudeColumn:
+			bounds = append(bounds, bound{i, -90, 90})
+		case column == longitudeColumn:
+			bounds = append(bounds, bound{i, -180, 180})
+		case strings.HasPrefix(column, fuel.PricePrefix):
+			bounds = append(bounds, bound{i, prices.min, prices.max})
+		}
+	}
+
+	var violations []rangeViolation
+	kept := rows[:0]
+	for i, row := range rows {
+		dropped := false
+		for _, b := range bounds {
+			raw := strings.TrimSpace(fieldAt(row, b.index))
+			if raw == "" {
+				continue
+			}
+			value, err := parseFloat(raw)
+			if err != nil || (value >= b.min && value <= b.max) {
+				continue
+			}
+			violation := rangeViolation{row: i + 1, column: header[b.index], value: value, min: b.min, max: b.max}
+			if siteIndex >= 0 {
+				violation.siteID = fieldAt(row, siteIndex)
+			}
+			violations = append(violations, violation)
+			dropped = true
+			break
+		}
+		if !dropped {
+			kept = append(kept, row)
+		}
+	}
+
+	output, err := fuel.WriteCSV(header, kept)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return output, len(kept), violations, nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// regionColumn is the column added by -enrich-region.
+const regionColumn = "region"
+
+// postcodeRegions maps each postcode area, the leading letters of a
+// postcode, to an ITL1 region name. Areas that cross a regional boundary
+// are given the region covering most of them.
+//
+//go:embed postcode-regions.csv
+var postcodeRegions []byte
+
+var loadRegions = sync.OnceValues(func() (map[string]string, error) {
+	header, rows, err := fuel.ReadCSV(postcodeRegions)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Equal(header, []string{"area", "region"}) {
+		return nil, fmt.Errorf("unexpected header %v", header)
+	}
+	regions := make(map[string]string, len(rows))
+	for _, row := range rows {
+		regions[row[0]] = row[1]
+	}
+	return regions, nil
+})
+
+// postcodeArea returns the leading letters of postcode, such as "NP" for
+// "NP4 6JU" or "G" for "G2 1AA".
+func postcodeArea(postcode string) string {
+	postcode = strings.ToUpper(strings.TrimSpace(postcode))
+	end := 0
+	for end < len(postcode) && end < 2 && postcode[end] >= 'A' && postcode[end] <= 'Z' {
+		end++
+	}
+	return postcode[:end]
+}
+
+// enrichRegion appends a region column looked up from each site's postcode
+// area. Sites with a missing or unknown postcode get an empty region,
+// written as null in the typed formats. The column name has no dots, so it
+// becomes a top-level key in the nested formats.
+func enrichRegion(payload []byte) ([]byte, error) {
+	regions, err := loadRegions()
+	if err != nil {
+		return nil, fmt.Errorf("load postcode regions: %w", err)
+	}
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return nil, err
+	}
+	index := slices.Index(header, postcodeColumn)
+	if index < 0 {
+		return nil, fmt.Errorf("missing column %s", postcodeColumn)
+	}
+	if slices.Contains(header, regionColumn) {
+		return nil, fmt.Errorf("column %s already exists", regionColumn)
+	}
+
+	for i, row := range rows {
+		rows[i] = append(row, regions[postcodeArea(fieldAt(row, index))])
+	}
+	return fuel.WriteCSV(append(slices.Clone(header), regionColumn), rows)
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// parseRenames turns "old=new" strings into a map from source column to
+// output name.
+func parseRenames(values []string) (map[string]string, error) {
+	renames := make(map[string]string, len(values))
+	for _, value := range values {
+		from, to, ok := strings.Cut(value, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("expected old=new but got %q", value)
+		}
+		if _, ok := renames[from]; ok {
+			return nil, fmt.Errorf("column %s renamed twice", from)
+		}
+		renames[from] = to
+	}
+	return renames, nil
+}
+
+// checkRenames fails if a renamed column is missing from header or if two
+// columns would be written under the same name.
+func checkRenames(header []string, renames map[string]string) error {
+	present := make(map[string]bool, len(header))
+	for _, key := range header {
+		present[key] = true
+	}
+	for from := range renames {
+		if !present[from] {
+			return fmt.Errorf("missing column %s", from)
+		}
+	}
+
+	opts := fuel.ConvertOptions{Renames: renames}
+	seen := make(map[string]string, len(header))
+	for _, key := range header {
+		name := opts.OutputName(key)
+		if previous, ok := seen[name]; ok {
+			return fmt.Errorf("columns %s and %s both map to %s", previous, key, name)
+		}
+		seen[name] = key
+	}
+	return nil
+}
+
+// flattenNames replaces the dots in each of names with sep.
+func flattenNames(names []string, sep string) []string {
+	flat := make([]string, len(names))
+	for i, name := range names {
+		flat[i] = strings.ReplaceAll(name, ".", sep)
+	}
+	return flat
+}
+
+// checkFlattenedNames fails if replacing dots with sep would write two of
+// names under the same name.
+func checkFlattenedNames(names []string, sep string) error {
+	seen := make(map[string]string, len(names))
+	for i, flat := range flattenNames(names, sep) {
+		if previous, ok := seen[flat]; ok {
+			return fmt.Errorf("columns %s and %s both map to %s", previous, names[i], flat)
+		}
+		seen[flat] = names[i]
+	}
+	return nil
+}
@@ -0,0 +1,707 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// runner fetches, processes and writes the feed once per run, sharing the
+// HTTP client and, with -serve, the served data between runs.
+type runner struct {
+	opts   *options
+	stderr io.Writer
+	client *http.Client
+	fetch  fuel.FetchOptions
+	server *dataServer
+	// spreadPending is set until the first run has waited for -spread.
+	spreadPending bool
+}
+
+func newRunner(o *options, stderr io.Writer) *runner {
+	r := &runner{
+		opts:   o,
+		stderr: stderr,
+		client: newHTTPClient(o.clientTimeout, o.proxyURL, o.tlsConfig),
+		fetch: fuel.FetchOptions{
+			Source:           o.source,
+			ProxyTemplate:    o.proxyTemplate,
+			Credentials:      o.credentials,
+			Retries:          o.retries,
+			RetryBase:        o.retryBase,
+			TargetTimeout:    o.targetTimeout,
+			MaxRetryAfter:    o.maxRetryAfter,
+			RateLimitRetries: o.rateLimitRetries,
+			MaxBytes:         o.maxBytes,
+			UserAgent:        o.userAgent,
+			Headers:          o.requestHeaders,
+		},
+		spreadPending: o.spread > 0 && o.inputPath == "",
+	}
+	if o.trace {
+		r.fetch.Trace = stderr
+	}
+	if o.serveAddr != "" {
+		r.server = &dataServer{opts: o.convert}
+	}
+	return r
+}
+
+// snapshot is the feed of one run after processing, ready to be written.
+type snapshot struct {
+	result    *fuel.Result
+	payload   []byte
+	rows      int
+	brands    map[string]int
+	fetchedAt time.Time
+}
+
+// run fetches or reads the feed and then processes and writes it, or stops
+// early for -list-columns, -validate-only and an unchanged feed.
+func (r *runner) run(ctx context.Context) error {
+	o := r.opts
+
+	// Many archivers are scheduled at the top of the hour; a random
+	// delay before the first fetch keeps them from arriving together.
+	if r.spreadPending {
+		r.spreadPending = false
+		wait := rand.N(o.spread)
+		slog.Info("waiting for -spread", "wait", wait.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("interrupted: %w", ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+
+	// Space fetches at least -min-interval apart, even across
+	// restarts, so a crash loop cannot hammer the endpoint. The
+	// marker is written before fetching so that failed attempts
+	// count too.
+	if o.minInterval > 0 && o.inputPath == "" {
+		last, err := loadFetchMarker(o.fetchMarker)
+		if err != nil {
+			return fetchError(fmt.Errorf("read fetch marker: %w", err))
+		}
+		if wait := time.Until(last.Add(o.minInterval)); wait > 0 {
+			slog.Info("waiting for -min-interval", "wait", wait.Round(time.Second))
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("interrupted: %w", ctx.Err())
+			case <-time.After(wait):
+			}
+		}
+		if err := saveFetchMarker(o.fetchMarker, time.Now()); err != nil {
+			return writeError(fmt.Errorf("write fetch marker: %w", err))
+		}
+	}
+
+	// Retailer feeds are fetched in the background while the main
+	// feed is fetched and validated. Returning early cancels them.
+	var merged func() ([]*retailerFeed, error)
+	if len(o.mergeURLs) > 0 {
+		var mergeFeeds []*retailerFeed
+		var mergeErr error
+		mergeDone := make(chan struct{})
+		mergeCtx, cancelMerge := context.WithCancel(ctx)
+		defer cancelMerge()
+		// Credentials and -header values are meant for the source feed,
+		// not for third-party retailers.
+		mergeOpts := r.fetch
+		mergeOpts.Credentials = nil
+		mergeOpts.Headers = nil
+		go func() {
+			defer close(mergeDone)
+			mergeFeeds, mergeErr = fetchRetailerFeeds(mergeCtx, r.client, o.mergeURLs, mergeOpts, o.concurrency)
+		}()
+		merged = func() ([]*retailerFeed, error) {
+			<-mergeDone
+			return mergeFeeds, mergeErr
+		}
+	}
+
+	var result *fuel.Result
+	var err error
+	if o.inputPath != "" {
+		payload, err := readInput(o.inputPath)
+		if err != nil {
+			return fetchError(fmt.Errorf("read input: %w", err))
+		}
+		slog.Info("read input", "path", o.inputPath, "bytes", len(payload))
+		result = &fuel.Result{Payload: payload, Source: o.inputPath}
+	} else {
+		conditional := r.fetch
+		if o.cacheMetaPath != "" && !o.listColumns && !o.validateOnly && r.server == nil && outputsExist(o.outputs) {
+			meta, err := loadCacheMeta(o.cacheMetaPath)
+			if err != nil {
+				return fetchError(fmt.Errorf("read cache metadata: %w", err))
+			}
+			conditional.Cache = meta
+		}
+
+		result, err = fuel.Fetch(ctx, r.client, conditional)
+		if errors.Is(err, fuel.ErrNotModified) {
+			if o.metricsPath != "" && !o.dryRun {
+				if err := recordMetrics(o.metricsPath, runMetrics{success: true, forecourts: -1}); err != nil {
+					return writeError(err)
+				}
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("interrupted: %w", ctx.Err())
+		}
+		if err != nil {
+			if o.metricsPath != "" && !o.dryRun {
+				if err := recordMetrics(o.metricsPath, runMetrics{forecourts: -1}); err != nil {
+					return writeError(err)
+				}
+			}
+			if o.fallbackLast && o.outPath != "-" && outputsExist(o.outputs) {
+				slog.Warn("fetch failed, keeping previous output", "path", o.outPath, "error", err)
+				if o.dryRun {
+					return nil
+				}
+				now := time.Now()
+				for _, target := range o.outputs {
+					if err := os.Chtimes(target.path, now, now); err != nil {
+						return writeError(fmt.Errorf("touch previous output: %w", err))
+					}
+				}
+				return nil
+			}
+			return fetchError(err)
+		}
+	}
+	payload := result.Payload
+	fetchedAt := time.Now()
+	if o.trace {
+		// Covers everything after the fetch: parsing, filtering,
+		// conversion and writing.
+		defer func() {
+			fmt.Fprintf(r.stderr, "trace processing: total %v\n", time.Since(fetchedAt).Round(time.Microsecond))
+		}()
+	}
+
+	if o.charset != "" {
+		payload, err = decodeCharset(payload, o.charset)
+		if err != nil {
+			return validationError(fmt.Errorf("decode %s: %w", o.charset, err))
+		}
+	}
+
+	if o.listColumns {
+		header, err := fuel.NewCSVReader(payload).Read()
+		if err != nil {
+			return validationError(fmt.Errorf("read header: %w", err))
+		}
+		for _, column := range header {
+			if kind := o.convert.ColumnType(column); kind != "" {
+				fmt.Printf("%s\t%s\n", column, kind)
+			} else {
+				fmt.Println(column)
+			}
+		}
+		return nil
+	}
+
+	var checkHeader func(header []string) error
+	if o.schema != nil {
+		checkHeader = o.schema.check
+	}
+
+	if o.validateOnly {
+		var prices *priceRange
+		if o.validateRanges != "" {
+			prices = &priceRange{min: o.priceMin, max: o.priceMax}
+		}
+		report, err := validateFeed(payload, checkHeader, prices, splitList(o.require), o.convert.ConvertOptions)
+		if err != nil {
+			return validationError(fmt.Errorf("invalid CSV: %w", err))
+		}
+		report.Source = result.Source
+		if err := report.write(os.Stdout); err != nil {
+			return writeError(fmt.Errorf("write report: %w", err))
+		}
+		if !report.Passed {
+			return validationError(fmt.Errorf("feed failed validation: %d problems", report.ProblemCount))
+		}
+		return nil
+	}
+
+	snap, err := r.process(result, payload, checkHeader, fetchedAt, merged)
+	if err != nil {
+		return err
+	}
+	return r.write(ctx, snap)
+}
+
+// process validates, filters and transforms payload, the decoded body of
+// result. merged, if not nil, waits for the -merge feeds fetched in the
+// background.
+func (r *runner) process(result *fuel.Result, payload []byte, checkHeader func(header []string) error, fetchedAt time.Time, merged func() ([]*retailerFeed, error)) (*snapshot, error) {
+	o := r.opts
+
+	rowCount, err := fuel.Validate(payload, checkHeader)
+	if err != nil {
+		return nil, validationError(fmt.Errorf("invalid CSV: %w", err))
+	}
+	slog.Info("parsed rows", "rows", rowCount)
+	if o.skipBad {
+		var bad []badRow
+		payload, rowCount, bad, err = skipBadRows(payload)
+		if err != nil {
+			return nil, validationError(fmt.Errorf("skip bad rows: %w", err))
+		}
+		for _, row := range bad {
+			fmt.Fprintf(r.stderr, "skipped %s\n", row)
+		}
+	}
+	if rowCount < o.minRows {
+		return nil, validationError(fmt.Errorf("feed has %d rows, fewer than -min-rows %d; leaving the output untouched", rowCount, o.minRows))
+	}
+
+	if merged != nil {
+		mergeFeeds, err := merged()
+		if err != nil {
+			return nil, fetchError(err)
+		}
+		var added int
+		payload, added, err = mergeRetailerFeeds(payload, mergeFeeds)
+		if err != nil {
+			return nil, validationError(fmt.Errorf("merge feeds: %w", err))
+		}
+		rowCount += added
+		slog.Info("merged retailer feeds", "feeds", len(mergeFeeds), "rows", added)
+	}
+
+	if list := splitList(o.require); len(list) > 0 {
+		var missing int
+		payload, rowCount, err = filterRows(payload, []rowFilter{requiredFilter(list, &missing)})
+		if err != nil {
+			return nil, validationError(fmt.Errorf("check required fields: %w", err))
+		}
+		if missing > 0 && o.requireMode == "fail" {
+			return nil, validationError(fmt.Errorf("%d rows missing required fields %s", missing, strings.Join(list, ", ")))
+		}
+		fmt.Fprintf(r.stderr, "dropped %d rows missing required fields\n", missing)
+	}
+
+	if o.fixCoordSwap {
+		var swaps []coordSwap
+		payload, swaps, err = fixCoordSwaps(payload, o.bounds)
+		if err != nil {
+			return nil, validationError(fmt.Errorf("fix coordinate swaps: %w", err))
+		}
+		for _, swap := range swaps {
+			fmt.Fprintf(r.stderr, "swapped %s\n", swap)
+		}
+	}
+
+	if o.validateRanges != "" {
+		var violations []rangeViolation
+		payload, rowCount, violations, err = checkRanges(payload, priceRange{min: o.priceMin, max: o.priceMax})
+		if err != nil {
+			return nil, validationError(fmt.Errorf("validate ranges: %w", err))
+		}
+		if len(violations) > 0 && o.validateRanges == "fail" {
+			return nil, validationError(fmt.Errorf("%d rows out of range, first at %s", len(violations), violations[0]))
+		}
+		for _, violation := range violations {
+			fmt.Fprintf(r.stderr, "dropped %s\n", violation)
+		}
+	}
+
+	if o.deriveAvail {
+		payload, err = deriveAvailability(payload)
+		if err != nil {
+			return nil, validationError(fmt.Errorf("derive availability: %w", err))
+		}
+	}
+
+	if o.enrichRegions {
+		payload, err = enrichRegion(payload)
+		if err != nil {
+			return nil, validationError(fmt.Errorf("enrich regions: %w", err))
+		}
+	}
+
+	// The dedupe filter remembers the sites it has seen, so it is
+	// created afresh for every run.
+	runFilters := o.filters
+	var duplicates int
+	if o.dedupe {
+		runFilters = append(slices.Clip(o.filters), dedupeFilter(o.dedupeKey, &duplicates))
+	}
+	if len(runFilters) > 0 {
+		var count int
+		payload, count, err = filterRows(payload, runFilters)
+		if err != nil {
+			return nil, validationError(fmt.Errorf("filter rows: %w", err))
+		}
+		if count == 0 {
+			return nil, validationError(errors.New("no forecourts match the filters"))
+		}
+		rowCount = count
+		slog.Info("filtered rows", "rows", count)
+	}
+	if o.dedupe {
+		fmt.Fprintf(r.stderr, "dropped %d duplicate rows by %s\n", duplicates, o.dedupeKey)
+	}
+
+	if o.sortColumn != "" {
+		payload, err = sortRows(payload, o.sortColumn, o.sortDesc, o.convert.ConvertOptions)
+		if err != nil {
+			return nil, validationError(fmt.Errorf("sort rows: %w", err))
+		}
+	}
+
+	if o.showStats {
+		if err := printStats(r.stderr, payload, o.convert.ConvertOptions); err != nil {
+			return nil, validationError(fmt.Errorf("compute stats: %w", err))
+		}
+	}
+
+	if o.diffPath != "" {
+		previous, err := readInput(o.diffPath)
+		if err != nil {
+			return nil, validationError(fmt.Errorf("read previous snapshot: %w", err))
+		}
+		if err := printDiff(r.stderr, previous, payload); err != nil {
+			return nil, validationError(fmt.Errorf("diff snapshots: %w", err))
+		}
+	}
+
+	if o.deltaPath != "" {
+		previous, err := readInput(o.deltaPath)
+		if err != nil {
+			return nil, validationError(fmt.Errorf("read previous snapshot: %w", err))
+		}
+		payload, err = addPriceDeltas(previous, payload)
+		if err != nil {
+			return nil, validationError(fmt.Errorf("add price deltas: %w", err))
+		}
+	}
+
+	if o.historyDB != "" {
+		payload, err = recordHistory(o.historyDB, payload, fetchedAt, o.historyStats, !o.dryRun)
+		if err != nil {
+			return nil, writeError(fmt.Errorf("record history: %w", err))
+		}
+	}
+
+	var brandCounts map[string]int
+	if o.metricsPath != "" || r.server != nil {
+		header, rows, err := fuel.ReadCSV(payload)
+		if err != nil {
+			return nil, validationError(fmt.Errorf("count brands: %w", err))
+		}
+		brandCounts = countBrands(header, rows)
+	}
+
+	if list := splitList(o.columns); len(list) > 0 {
+		payload, err = selectColumns(payload, list)
+		if err != nil {
+			return nil, validationError(fmt.Errorf("select columns: %w", err))
+		}
+	}
+
+	if len(o.renames) > 0 || len(o.upperColumns) > 0 || len(o.lowerColumns) > 0 || len(o.columnTypes) > 0 {
+		header, err := fuel.NewCSVReader(payload).Read()
+		if err != nil {
+			return nil, validationError(fmt.Errorf("read header: %w", err))
+		}
+		if err := checkRenames(header, o.renames); err != nil {
+			return nil, validationError(fmt.Errorf("invalid -rename: %w", err))
+		}
+		if err := checkColumnsPresent(header, o.upperColumns); err != nil {
+			return nil, validationError(fmt.Errorf("invalid -upper: %w", err))
+		}
+		if err := checkColumnsPresent(header, o.lowerColumns); err != nil {
+			return nil, validationError(fmt.Errorf("invalid -lower: %w", err))
+		}
+		for column, kind := range o.columnTypes {
+			if !slices.Contains(header, column) {
+				return nil, validationError(fmt.Errorf("invalid -%s: missing column %s", kind, column))
+			}
+		}
+	}
+
+	if o.timestampField != "" {
+		payload, err = appendColumn(payload, o.timestampField, fetchedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, validationError(fmt.Errorf("add %s: %w", o.timestampField, err))
+		}
+	}
+
+	if o.flattenSep != "" {
+		header, err := fuel.NewCSVReader(payload).Read()
+		if err != nil {
+			return nil, validationError(fmt.Errorf("read header: %w", err))
+		}
+		if err := checkFlattenedNames(o.convert.OutputNames(header), o.flattenSep); err != nil {
+			return nil, validationError(fmt.Errorf("invalid -flatten-sep: %w", err))
+		}
+	}
+
+	if slices.Contains(o.formats, "md") && rowCount > markdownWarnRows {
+		slog.Warn("Markdown tables do not scale to this many rows; consider filtering first", "rows", rowCount)
+	}
+
+	return &snapshot{result: result, payload: payload, rows: rowCount, brands: brandCounts, fetchedAt: fetchedAt}, nil
+}
+
+// write hands snap to the data server or writes it to every output, or
+// only reports what would be written with -dry-run.
+func (r *runner) write(ctx context.Context, snap *snapshot) error {
+	o := r.opts
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted: %w", ctx.Err())
+	}
+
+	var parts []splitPart
+	if o.splitBy != "" {
+		var err error
+		parts, err = splitRows(snap.payload, o.splitBy)
+		if err != nil {
+			return validationError(fmt.Errorf("split rows: %w", err))
+		}
+	}
+
+	if o.dryRun && parts != nil {
+		fmt.Fprintf(r.stderr, "dry run: %d rows split into %d files by %s, nothing written\n", snap.rows, len(parts), o.splitBy)
+		return nil
+	}
+
+	if o.dryRun {
+		for _, f := range o.formats {
+			if f == "sqlite" {
+				if err := writeSQLite(":memory:", snap.payload, o.convert); err != nil {
+					return validationError(fmt.Errorf("write SQLite: %w", err))
+				}
+				fmt.Fprintf(r.stderr, "dry run: %d rows as sqlite, nothing written\n", snap.rows)
+				continue
+			}
+			counter := &countingWriter{w: io.Discard}
+			if err := writeFormatted(counter, f, snap.payload, o.compress, o.convert); err != nil {
+				return validationError(err)
+			}
+			fmt.Fprintf(r.stderr, "dry run: %d rows, %d bytes as %s, nothing written\n", snap.rows, counter.n, f)
+		}
+		return nil
+	}
+
+	if o.keepRaw != "" {
+		if err := writeFileAtomic(o.keepRaw, snap.result.Payload, o.fileMode); err != nil {
+			return writeError(fmt.Errorf("write raw CSV: %w", err))
+		}
+		slog.Info("wrote raw CSV", "path", o.keepRaw, "bytes", len(snap.result.Payload))
+	}
+
+	if r.server != nil {
+		if err := r.server.update(snap.payload, snap.rows, snap.brands, snap.fetchedAt); err != nil {
+			return validationError(err)
+		}
+		slog.Info("refreshed served data", "rows", snap.rows)
+		return nil
+	}
+
+	if parts != nil {
+		if err := os.MkdirAll(o.outDir, 0o755); err != nil {
+			return writeError(fmt.Errorf("create output directory: %w", err))
+		}
+		var paths []string
+		for _, f := range o.formats {
+			written, err := writeSplitOutputs(o.outDir, parts, f, o.compress, o.convert, o.fileMode)
+			if err != nil {
+				return writeError(err)
+			}
+			paths = append(paths, written...)
+		}
+		if o.checksum != "" {
+			for _, path := range paths {
+				sum, err := hashFile(path, o.checksum)
+				if err != nil {
+					return writeError(fmt.Errorf("read output for checksum: %w", err))
+				}
+				if err := writeChecksum(path, o.checksum, sum); err != nil {
+					return writeError(fmt.Errorf("write checksum: %w", err))
+				}
+			}
+		}
+		fmt.Fprintf(r.stderr, "wrote %d files to %s\n", len(paths), o.outDir)
+		if o.metricsPath != "" {
+			if err := recordMetrics(o.metricsPath, runMetrics{success: true, forecourts: snap.rows, brands: snap.brands}); err != nil {
+				return writeError(err)
+			}
+		}
+		return nil
+	}
+
+	for _, target := range o.outputs {
+		if err := r.writeOutput(ctx, target, snap); err != nil {
+			return err
+		}
+	}
+
+	if err := saveFetchCache(o.cacheMetaPath, snap.result.Cache); err != nil {
+		return writeError(err)
+	}
+
+	if o.metricsPath != "" {
+		if err := recordMetrics(o.metricsPath, runMetrics{success: true, forecourts: snap.rows, brands: snap.brands}); err != nil {
+			return writeError(err)
+		}
+	}
+	return nil
+}
+
+// writeOutput writes one format to its path, with its checksum and
+// manifest. The fetch cache and metrics are recorded once all outputs are
+// written.
+func (r *runner) writeOutput(ctx context.Context, target outputTarget, snap *snapshot) error {
+	o := r.opts
+
+	if target.format == "sqlite" {
+		if err := writeSQLite(target.path, snap.payload, o.convert); err != nil {
+			return writeError(fmt.Errorf("write SQLite: %w", err))
+		}
+		if err := os.Chmod(target.path, o.fileMode); err != nil {
+			return writeError(fmt.Errorf("set output mode: %w", err))
+		}
+		slog.Info("wrote output", "path", target.path, "format", target.format)
+		if o.checksum != "" {
+			sum, err := hashFile(target.path, o.checksum)
+			if err != nil {
+				return writeError(fmt.Errorf("read output for checksum: %w", err))
+			}
+			if err := writeChecksum(target.path, o.checksum, sum); err != nil {
+				return writeError(fmt.Errorf("write checksum: %w", err))
+			}
+		}
+		if o.manifestPath != "" {
+			info, err := os.Stat(target.path)
+			if err != nil {
+				return writeError(fmt.Errorf("read output for manifest: %w", err))
+			}
+			sum, err := hashFile(target.path, "sha256")
+			if err != nil {
+				return writeError(fmt.Errorf("read output for manifest: %w", err))
+			}
+			if err := recordManifest(o.manifestPath, runManifest{
+				Source:    snap.result.Source,
+				FetchedAt: snap.fetchedAt,
+				Status:    snap.result.Status,
+				Output:    target.path,
+				Format:    target.format,
+				Bytes:     info.Size(),
+				Rows:      snap.rows,
+				SHA256:    hex.EncodeToString(sum),
+			}); err != nil {
+				return writeError(err)
+			}
+		}
+		return nil
+	}
+
+	// Render up front so an identical file is never rewritten, then
+	// write the rendered bytes rather than converting again.
+	var rendered []byte
+	if o.onlyIfChanged {
+		var buf bytes.Buffer
+		if err := writeFormatted(&buf, target.format, snap.payload, o.compress, o.convert); err != nil {
+			return writeError(err)
+		}
+		rendered = buf.Bytes()
+		sum := sha256.Sum256(rendered)
+		existing, err := hashFile(target.path, "sha256")
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return writeError(fmt.Errorf("read output for comparison: %w", err))
+		}
+		if bytes.Equal(existing, sum[:]) {
+			fmt.Fprintf(r.stderr, "output unchanged, not rewriting %s\n", target.path)
+			return nil
+		}
+	}
+
+	var sink outputSink
+	var err error
+	switch {
+	case o.appendOutput:
+		sink = &appendFile{path: target.path, perm: o.fileMode}
+	case isS3URL(target.path):
+		sink, err = openS3Object(ctx, target.path, contentType(target.format, o.compress))
+	default:
+		sink, err = openOutput(target.path, o.fileMode)
+	}
+	if err != nil {
+		return writeError(fmt.Errorf("write output: %w", err))
+	}
+	counter := &countingWriter{w: sink}
+	writers := []io.Writer{counter}
+	var digest, manifestDigest hash.Hash
+	if o.checksum != "" {
+		digest, _ = newChecksumHash(o.checksum)
+		writers = append(writers, digest)
+	}
+	if o.manifestPath != "" {
+		manifestDigest = sha256.New()
+		writers = append(writers, manifestDigest)
+	}
+	w := io.MultiWriter(writers...)
+	if rendered != nil {
+		if _, err := w.Write(rendered); err != nil {
+			sink.Abort()
+			return writeError(fmt.Errorf("write output: %w", err))
+		}
+	} else if err := writeFormatted(w, target.format, snap.payload, o.compress, o.convert); err != nil {
+		sink.Abort()
+		return writeError(err)
+	}
+	if err := sink.Commit(); err != nil {
+		return writeError(fmt.Errorf("write output: %w", err))
+	}
+	slog.Info("wrote output", "path", target.path, "format", target.format, "bytes", counter.n)
+
+	if digest != nil {
+		if err := writeChecksum(target.path, o.checksum, digest.Sum(nil)); err != nil {
+			return writeError(fmt.Errorf("write checksum: %w", err))
+		}
+	}
+
+	if manifestDigest != nil {
+		if err := recordManifest(o.manifestPath, runManifest{
+			Source:    snap.result.Source,
+			FetchedAt: snap.fetchedAt,
+			Status:    snap.result.Status,
+			Output:    target.path,
+			Format:    target.format,
+			Bytes:     counter.n,
+			Rows:      snap.rows,
+			SHA256:    hex.EncodeToString(manifestDigest.Sum(nil)),
+		}); err != nil {
+			return writeError(err)
+		}
+	}
+	return nil
+}
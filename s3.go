@@ -0,0 +1,82 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// isS3URL reports whether the output path names an S3 object.
+func isS3URL(path string) bool {
+	return strings.HasPrefix(path, "s3://")
+}
+
+// s3Object buffers the output and uploads it to an S3 bucket on Commit.
+// Credentials, region and endpoint come from the standard AWS environment
+// variables, shared config files or the instance profile; set
+// FUEL_S3_PATH_STYLE=true for S3-compatible stores that need path-style
+// addressing.
+type s3Object struct {
+	bytes.Buffer
+	ctx         context.Context
+	bucket      string
+	key         string
+	contentType string
+}
+
+// parseS3URL splits an s3://bucket/key path into its bucket and key.
+func parseS3URL(raw string) (string, string, error) {
+	target, err := url.Parse(raw)
+	if err != nil {
+		return "", "", err
+	}
+	key := strings.TrimPrefix(target.Path, "/")
+	if target.Host == "" || key == "" {
+		return "", "", errors.New("expected s3://bucket/key")
+	}
+	return target.Host, key, nil
+}
+
+func openS3Object(ctx context.Context, raw, contentType string) (*s3Object, error) {
+	bucket, key, err := parseS3URL(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Object{ctx: ctx, bucket: bucket, key: key, contentType: contentType}, nil
+}
+
+func (o *s3Object) Commit() error {
+	cfg, err := config.LoadDefaultConfig(o.ctx)
+	if err != nil {
+		return fmt.Errorf("load AWS config: %w", err)
+	}
+	pathStyle := os.Getenv("FUEL_S3_PATH_STYLE") == "true"
+	client := s3.NewFromConfig(cfg, func(opts *s3.Options) {
+		opts.UsePathStyle = pathStyle
+	})
+
+	_, err = client.PutObject(o.ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(o.bucket),
+		Key:         aws.String(o.key),
+		Body:        bytes.NewReader(o.Bytes()),
+		ContentType: aws.String(o.contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("upload s3://%s/%s: %w", o.bucket, o.key, err)
+	}
+	return nil
+}
+
+func (o *s3Object) Abort() { o.Reset() }
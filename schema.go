@@ -0,0 +1,82 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// columnSchema is the set of header columns the upstream CSV is expected to
+// carry, loaded from a file with one column name per line.
+type columnSchema struct {
+	columns  []string
+	anyOrder bool
+}
+
+func loadSchema(path string, anyOrder bool) (*columnSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &columnSchema{anyOrder: anyOrder}
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if slices.Contains(schema.columns, line) {
+			return nil, fmt.Errorf("line %d: duplicate column %s", n+1, line)
+		}
+		schema.columns = append(schema.columns, line)
+	}
+	if len(schema.columns) == 0 {
+		return nil, errors.New("schema lists no columns")
+	}
+	return schema, nil
+}
+
+// check compares header against the schema, reporting missing and
+// unexpected columns, and the first out-of-order column unless anyOrder is
+// set.
+func (s *columnSchema) check(header []string) error {
+	var problems []string
+	if missing := subtractColumns(s.columns, header); len(missing) > 0 {
+		problems = append(problems, "missing columns: "+strings.Join(missing, ", "))
+	}
+	if unexpected := subtractColumns(header, s.columns); len(unexpected) > 0 {
+		problems = append(problems, "unexpected columns: "+strings.Join(unexpected, ", "))
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	if len(header) != len(s.columns) {
+		return fmt.Errorf("header has %d columns, expected %d", len(header), len(s.columns))
+	}
+
+	if s.anyOrder {
+		return nil
+	}
+	for i, column := range s.columns {
+		if header[i] != column {
+			return fmt.Errorf("column %d is %s, expected %s", i+1, header[i], column)
+		}
+	}
+	return nil
+}
+
+func subtractColumns(columns, remove []string) []string {
+	var result []string
+	for _, column := range columns {
+		if !slices.Contains(remove, column) {
+			result = append(result, column)
+		}
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// defaultServeInterval is how often -serve refreshes the data when
+// -interval is not set.
+const defaultServeInterval = 15 * time.Minute
+
+// dataSnapshot is the data from one successful run, rendered in every
+// served format.
+type dataSnapshot struct {
+	csv        []byte
+	json       []byte
+	fetchedAt  time.Time
+	forecourts int
+	brands     map[string]int
+}
+
+// dataServer serves the latest data from memory. Each run replaces the
+// snapshot, so a failed refresh keeps the previous data available.
+type dataServer struct {
+	opts convertOptions
+
+	mu       sync.RWMutex
+	snapshot *dataSnapshot
+	success  bool
+	finished time.Time
+}
+
+// update renders payload as CSV and JSON and makes it the served data.
+func (s *dataServer) update(payload []byte, forecourts int, brands map[string]int, fetchedAt time.Time) error {
+	csvOpts := s.opts
+	csvOpts.delimiter = ','
+	csvData, err := convertPayload("csv", payload, csvOpts)
+	if err != nil {
+		return err
+	}
+	jsonData, err := fuel.ToJSON(payload, s.opts.ConvertOptions)
+	if err != nil {
+		return fmt.Errorf("convert to JSON: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot = &dataSnapshot{
+		csv:        csvData,
+		json:       jsonData,
+		fetchedAt:  fetchedAt,
+		forecourts: forecourts,
+		brands:     brands,
+	}
+	s.success = true
+	s.finished = time.Now()
+	return nil
+}
+
+// fail records a failed refresh for /metrics.
+func (s *dataServer) fail() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.success = false
+	s.finished = time.Now()
+}
+
+func (s *dataServer) current() *dataSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshot
+}
+
+func (s *dataServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /data", func(w http.ResponseWriter, r *http.Request) {
+		if acceptsJSON(r.Header.Get("Accept")) {
+			s.serveData(w, r, "data.json")
+		} else {
+			s.serveData(w, r, "data.csv")
+		}
+	})
+	mux.HandleFunc("GET /data.csv", func(w http.ResponseWriter, r *http.Request) {
+		s.serveData(w, r, "data.csv")
+	})
+	mux.HandleFunc("GET /data.json", func(w http.ResponseWriter, r *http.Request) {
+		s.serveData(w, r, "data.json")
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		snapshot := s.current()
+		if snapshot == nil {
+			http.Error(w, "no data yet", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, "ok, fetched %s\n", snapshot.fetchedAt.UTC().Format(time.RFC3339))
+	})
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		m := runMetrics{success: s.success, forecourts: -1, finished: s.finished}
+		if s.snapshot != nil {
+			m.forecourts = s.snapshot.forecourts
+			m.brands = s.snapshot.brands
+		}
+		s.mu.RUnlock()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(formatMetrics(m))
+	})
+	return mux
+}
+
+// serveData writes the named rendering of the current snapshot. Going
+// through http.ServeContent answers If-Modified-Since and range requests.
+func (s *dataServer) serveData(w http.ResponseWriter, r *http.Request, name string) {
+	snapshot := s.current()
+	if snapshot == nil {
+		http.Error(w, "no data yet", http.StatusServiceUnavailable)
+		return
+	}
+	body := snapshot.csv
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	if name == "data.json" {
+		body = snapshot.json
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Header().Set("Vary", "Accept")
+	http.ServeContent(w, r, name, snapshot.fetchedAt, bytes.NewReader(body))
+}
+
+// acceptsJSON reports whether an Accept header asks for JSON. Quality
+// values are ignored; a header naming JSON at all is taken as preferring it.
+func acceptsJSON(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		mediaType = strings.TrimSpace(mediaType)
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// sortRows orders the rows by column, numerically when opts types it as a
+// number (prices and coordinates unless -string says otherwise) and
+// lexically otherwise. Rows without a numeric value sort last in either
+// direction and ties keep their upstream order.
+func sortRows(payload []byte, column string, desc bool, opts fuel.ConvertOptions) ([]byte, error) {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	index := slices.Index(header, column)
+	if index < 0 {
+		return nil, fmt.Errorf("missing column %s", column)
+	}
+
+	numeric := opts.ColumnType(column) == fuel.TypeNumeric
+	slices.SortStableFunc(rows, func(a, b []string) int {
+		left, right := fieldAt(a, index), fieldAt(b, index)
+		if !numeric {
+			return applyDirection(strings.Compare(left, right), desc)
+		}
+
+		leftValue, leftErr := parseFloat(left)
+		rightValue, rightErr := parseFloat(right)
+		switch {
+		case leftErr != nil && rightErr != nil:
+			return 0
+		case leftErr != nil:
+			return 1
+		case rightErr != nil:
+			return -1
+		}
+		return applyDirection(cmp.Compare(leftValue, rightValue), desc)
+	})
+
+	return fuel.WriteCSV(header, rows)
+}
+
+func applyDirection(result int, desc bool) int {
+	if desc {
+		return -result
+	}
+	return result
+}
+
+func fieldAt(row []string, index int) string {
+	if index >= 0 && index < len(row) {
+		return row[index]
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// splitPart is the CSV payload for one value of the -split-by column and
+// the file name it is written under, without an extension.
+type splitPart struct {
+	name    string
+	payload []byte
+}
+
+// splitRows groups the rows by the value of column, keeping the header in
+// every part. Parts are ordered by first appearance, and names that would
+// collide after sanitizing get a numeric suffix.
+func splitRows(payload []byte, column string) ([]splitPart, error) {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return nil, err
+	}
+	index := slices.Index(header, column)
+	if index < 0 {
+		return nil, fmt.Errorf("missing column %s", column)
+	}
+
+	var values []string
+	groups := make(map[string][][]string)
+	for _, row := range rows {
+		value := strings.TrimSpace(fieldAt(row, index))
+		if _, ok := groups[value]; !ok {
+			values = append(values, value)
+		}
+		groups[value] = append(groups[value], row)
+	}
+
+	parts := make([]splitPart, 0, len(values))
+	used := make(map[string]bool, len(values))
+	for _, value := range values {
+		base := sanitizeFileName(value)
+		name := base
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[name] = true
+		part, err := fuel.WriteCSV(header, groups[value])
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, splitPart{name: name, payload: part})
+	}
+	return parts, nil
+}
+
+// sanitizeFileName lowercases value and replaces every run of characters
+// other than letters and digits with a hyphen. An empty result becomes
+// "none".
+func sanitizeFileName(value string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(value) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		if !hyphen && b.Len() > 0 {
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "-")
+	if name == "" {
+		return "none"
+	}
+	return name
+}
+
+// writeSplitOutputs writes each part to dir as <name>.<format>, plus .gz
+// when compressing, and returns the paths written.
+func writeSplitOutputs(dir string, parts []splitPart, format string, compress bool, opts convertOptions, perm os.FileMode) ([]string, error) {
+	paths := make([]string, 0, len(parts))
+	for _, part := range parts {
+		path := filepath.Join(dir, part.name+"."+format)
+		if compress {
+			path += ".gz"
+		}
+
+		if format == "sqlite" {
+			if err := writeSQLite(path, part.payload, opts); err != nil {
+				return paths, fmt.Errorf("write %s: %w", path, err)
+			}
+			if err := os.Chmod(path, perm); err != nil {
+				return paths, fmt.Errorf("set mode of %s: %w", path, err)
+			}
+			paths = append(paths, path)
+			continue
+		}
+
+		sink, err := openOutput(path, perm)
+		if err != nil {
+			return paths, fmt.Errorf("write %s: %w", path, err)
+		}
+		if err := writeFormatted(sink, format, part.payload, compress, opts); err != nil {
+			sink.Abort()
+			return paths, fmt.Errorf("write %s: %w", path, err)
+		}
+		if err := sink.Commit(); err != nil {
+			return paths, fmt.Errorf("write %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
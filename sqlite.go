@@ -0,0 +1,97 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"fuelfinder-archive/pkg/fuel"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteTable = "forecourts"
+
+// writeSQLite loads the rows into a forecourts table in the database at
+// path, replacing any table left by a previous run. Dots in column names
+// become underscores; nullable numeric fields are REAL and everything else
+// is TEXT. Conversion failures are classified as validation errors, so
+// only database failures are left for the caller to treat as write errors.
+func writeSQLite(path string, payload []byte, opts convertOptions) error {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return validationError(err)
+	}
+
+	names, err := underscoreColumns(opts.OutputNames(header))
+	if err != nil {
+		return validationError(err)
+	}
+
+	columns := make([]string, len(header))
+	for i, key := range header {
+		columnType := "TEXT"
+		if opts.ColumnType(key) == fuel.TypeNumeric {
+			columnType = "REAL"
+		}
+		columns[i] = quoteIdentifier(names[i]) + " " + columnType
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DROP TABLE IF EXISTS " + quoteIdentifier(sqliteTable)); err != nil {
+		return fmt.Errorf("drop table: %w", err)
+	}
+	create := fmt.Sprintf("CREATE TABLE %s (%s)", quoteIdentifier(sqliteTable), strings.Join(columns, ", "))
+	if _, err := tx.Exec(create); err != nil {
+		return fmt.Errorf("create table: %w", err)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(header)), ", ")
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s VALUES (%s)", quoteIdentifier(sqliteTable), placeholders))
+	if err != nil {
+		return fmt.Errorf("prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	args := make([]any, len(header))
+	for r, row := range rows {
+		if len(row) != len(header) {
+			return validationError(fmt.Errorf("output row %d: row has %d fields, expected %d", r+1, len(row), len(header)))
+		}
+		for i, key := range header {
+			if opts.ColumnType(key) != fuel.TypeNumeric {
+				args[i] = fuel.NormalizeString(key, row[i], opts.ConvertOptions)
+				continue
+			}
+			value, err := fuel.NormalizeField(key, row[i], opts.ConvertOptions)
+			if err != nil {
+				return validationError(fmt.Errorf("output row %d: %w", r+1, err))
+			}
+			args[i] = value
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			return fmt.Errorf("insert row: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,26 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSQLiteClassifiesBadValuesAsValidation(t *testing.T) {
+	payload := []byte("forecourts.node_id,forecourts.fuel_price.E10\n" +
+		"abc123,n/a\n")
+
+	err := writeSQLite(filepath.Join(t.TempDir(), "out.sqlite"), payload, convertOptions{})
+	var classified *exitError
+	if !errors.As(err, &classified) || classified.code != exitValidation {
+		t.Errorf("err = %v, want a validation error", err)
+	}
+	want := `output row 1: parse forecourts.fuel_price.E10 "n/a": invalid syntax`
+	if err == nil || err.Error() != want {
+		t.Errorf("err = %v, want %s", err, want)
+	}
+}
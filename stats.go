@@ -0,0 +1,115 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+type priceStats struct {
+	count int
+	min   float64
+	max   float64
+	sum   float64
+}
+
+func (p *priceStats) add(value float64) {
+	if p.count == 0 || value < p.min {
+		p.min = value
+	}
+	if p.count == 0 || value > p.max {
+		p.max = value
+	}
+	p.count++
+	p.sum += value
+}
+
+// printStats writes the forecourt count, the count per brand and the
+// min/max/mean of each fuel price column typed as a number by opts, under
+// its output name. Empty prices are skipped.
+func printStats(w io.Writer, payload []byte, opts fuel.ConvertOptions) error {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return err
+	}
+
+	brands := countBrands(header, rows)
+	var priceColumns []int
+	prices := make(map[int]*priceStats)
+	for i, column := range header {
+		if opts.ColumnType(column) == fuel.TypeNumeric && strings.HasPrefix(column, fuel.PricePrefix) {
+			priceColumns = append(priceColumns, i)
+			prices[i] = &priceStats{}
+		}
+	}
+
+	for _, row := range rows {
+		for _, index := range priceColumns {
+			raw := fieldAt(row, index)
+			if raw == "" {
+				continue
+			}
+			value, err := parseFloat(raw)
+			if err != nil {
+				return fmt.Errorf("parse %s: %w", header[index], err)
+			}
+			prices[index].add(value)
+		}
+	}
+
+	fmt.Fprintf(w, "forecourts: %d\n", len(rows))
+
+	if len(brands) > 0 {
+		fmt.Fprintln(w, "brands:")
+		names := slices.SortedFunc(maps.Keys(brands), func(a, b string) int {
+			return cmp.Or(cmp.Compare(brands[b], brands[a]), strings.Compare(a, b))
+		})
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s: %d\n", name, brands[name])
+		}
+	}
+
+	if len(priceColumns) > 0 {
+		fmt.Fprintln(w, "prices:")
+		for _, index := range priceColumns {
+			fuel := strings.TrimPrefix(opts.OutputName(header[index]), fuel.PricePrefix)
+			stats := prices[index]
+			if stats.count == 0 {
+				fmt.Fprintf(w, "  %s: no prices\n", fuel)
+				continue
+			}
+			fmt.Fprintf(w, "  %s: min %.2f max %.2f mean %.2f (%d forecourts)\n",
+				fuel, stats.min, stats.max, stats.sum/float64(stats.count), stats.count)
+		}
+	}
+
+	return nil
+}
+
+// countBrands returns the number of rows per brand, or nil when there is no
+// brand column.
+func countBrands(header []string, rows [][]string) map[string]int {
+	brandIndex := slices.Index(header, brandColumn)
+	if brandIndex < 0 {
+		return nil
+	}
+
+	brands := make(map[string]int)
+	for _, row := range rows {
+		brand := strings.TrimSpace(fieldAt(row, brandIndex))
+		if brand == "" {
+			brand = "(none)"
+		}
+		brands[brand]++
+	}
+	return brands
+}
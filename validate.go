@@ -0,0 +1,96 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// maxReportProblems limits how many problems the -validate-only report
+// lists; ProblemCount still counts them all.
+const maxReportProblems = 100
+
+// validationReport is the result of -validate-only, printed as JSON.
+type validationReport struct {
+	Passed       bool     `json:"passed"`
+	Source       string   `json:"source"`
+	Rows         int      `json:"rows"`
+	Columns      int      `json:"columns"`
+	ProblemCount int      `json:"problem_count"`
+	Problems     []string `json:"problems"`
+}
+
+func (r *validationReport) addProblem(format string, args ...any) {
+	r.ProblemCount++
+	if len(r.Problems) < maxReportProblems {
+		r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+	}
+}
+
+// validateFeed runs every check on payload without stopping at the first
+// problem: the header against checkHeader, the field count of each row,
+// coordinate and price ranges when prices is not nil, the required columns
+// and the typing of every value. Only a payload that cannot be read as CSV
+// at all is an error.
+func validateFeed(payload []byte, checkHeader func([]string) error, prices *priceRange, required []string, opts fuel.ConvertOptions) (*validationReport, error) {
+	report := &validationReport{Problems: []string{}}
+
+	header, err := fuel.NewCSVReader(payload).Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	report.Columns = len(header)
+	if checkHeader != nil {
+		if err := checkHeader(header); err != nil {
+			report.addProblem("header: %v", err)
+		}
+	}
+
+	payload, rows, bad, err := skipBadRows(payload)
+	if err != nil {
+		return nil, err
+	}
+	report.Rows = rows + len(bad)
+	for _, row := range bad {
+		report.addProblem("%s", row)
+	}
+
+	if prices != nil {
+		_, _, violations, err := checkRanges(payload, *prices)
+		if err != nil {
+			return nil, err
+		}
+		for _, violation := range violations {
+			report.addProblem("%s", violation)
+		}
+	}
+
+	if len(required) > 0 {
+		var missing int
+		if _, _, err := filterRows(payload, []rowFilter{requiredFilter(required, &missing)}); err != nil {
+			report.addProblem("required columns: %v", err)
+		} else if missing > 0 {
+			report.addProblem("%d rows missing required fields %s", missing, strings.Join(required, ", "))
+		}
+	}
+
+	if _, err := fuel.ParseRecords(payload, opts); err != nil {
+		report.addProblem("convert: %v", err)
+	}
+
+	report.Passed = report.ProblemCount == 0
+	return report, nil
+}
+
+func (r *validationReport) write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
@@ -0,0 +1,27 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// Populated at build time, for example:
+//
+//	go build -ldflags "-X main.version=1.0.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+func printVersion(w io.Writer) {
+	fmt.Fprintf(w, "fuelfinder-archive %s\n", version)
+	fmt.Fprintf(w, "commit: %s\n", commit)
+	fmt.Fprintf(w, "built: %s\n", buildDate)
+	fmt.Fprintf(w, "go: %s\n", runtime.Version())
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseMeta parses -meta key=value pairs into the static fields written
+// next to the -wrap key, which none of them may shadow.
+func parseMeta(values []string, wrap string) (map[string]string, error) {
+	meta := make(map[string]string, len(values))
+	for _, value := range values {
+		key, field, ok := strings.Cut(value, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected key=value but got %q", value)
+		}
+		if key == wrap {
+			return nil, fmt.Errorf("field %s clashes with the -wrap key", key)
+		}
+		if _, ok := meta[key]; ok {
+			return nil, fmt.Errorf("field %s given twice", key)
+		}
+		meta[key] = field
+	}
+	return meta, nil
+}
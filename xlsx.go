@@ -0,0 +1,84 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+
+	"fuelfinder-archive/pkg/fuel"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const xlsxSheet = "forecourts"
+
+// convertCSVToXLSX writes the rows as a single-sheet Excel workbook. The
+// header row is bold and frozen, prices and coordinates are numeric cells
+// (empty when missing) and every other value is a text cell, so Excel does
+// not reinterpret postcodes or timestamps.
+func convertCSVToXLSX(payload []byte, opts convertOptions) ([]byte, error) {
+	header, rows, err := fuel.ReadCSV(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	file := excelize.NewFile()
+	defer file.Close()
+	if err := file.SetSheetName(file.GetSheetName(0), xlsxSheet); err != nil {
+		return nil, err
+	}
+	bold, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	stream, err := file.NewStreamWriter(xlsxSheet)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return nil, err
+	}
+
+	cells := make([]any, len(header))
+	for i, name := range opts.OutputNames(header) {
+		cells[i] = excelize.Cell{StyleID: bold, Value: name}
+	}
+	if err := stream.SetRow("A1", cells); err != nil {
+		return nil, err
+	}
+
+	for r, row := range rows {
+		if len(row) != len(header) {
+			return nil, fmt.Errorf("output row %d: row has %d fields, expected %d", r+1, len(row), len(header))
+		}
+		for i, key := range header {
+			if opts.ColumnType(key) != fuel.TypeNumeric {
+				cells[i] = fuel.NormalizeString(key, row[i], opts.ConvertOptions)
+				continue
+			}
+			value, err := fuel.NormalizeField(key, row[i], opts.ConvertOptions)
+			if err != nil {
+				return nil, fmt.Errorf("output row %d: %w", r+1, err)
+			}
+			cells[i] = value
+		}
+		cell, err := excelize.CoordinatesToCellName(1, r+2)
+		if err != nil {
+			return nil, err
+		}
+		if err := stream.SetRow(cell, cells); err != nil {
+			return nil, err
+		}
+	}
+	if err := stream.Flush(); err != nil {
+		return nil, err
+	}
+
+	buf, err := file.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2026 Matthew Gall <me@matthewgall.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"fuelfinder-archive/pkg/fuel"
+)
+
+// convertCSVToXML writes a <forecourts> document with one <forecourt>
+// element per row, nesting dotted column names into child elements the same
+// way as the JSON output. Null values become empty elements.
+func convertCSVToXML(payload []byte, opts convertOptions) ([]byte, error) {
+	records, err := fuel.ParseRecords(payload, opts.ConvertOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+
+	root := xml.StartElement{Name: xml.Name{Local: "forecourts"}}
+	if err := encoder.EncodeToken(root); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if err := encodeXMLElement(encoder, "forecourt", record); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.EncodeToken(root.End()); err != nil {
+		return nil, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func encodeXMLElement(encoder *xml.Encoder, name string, value any) error {
+	if !isXMLName(name) {
+		return fmt.Errorf("%q is not a valid XML element name", name)
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			if err := encodeXMLElement(encoder, key, v[key]); err != nil {
+				return err
+			}
+		}
+	case nil:
+	case float64:
+		if err := encoder.EncodeToken(xml.CharData(strconv.FormatFloat(v, 'f', -1, 64))); err != nil {
+			return err
+		}
+	default:
+		if err := encoder.EncodeToken(xml.CharData(fmt.Sprint(v))); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// checkXMLName fails if any dotted part of a column name, each of which
+// becomes a nested element, is not a valid XML element name.
+func checkXMLName(name string) error {
+	for _, part := range strings.Split(name, ".") {
+		if !isXMLName(part) {
+			return fmt.Errorf("%q is not a valid XML element name", part)
+		}
+	}
+	return nil
+}
+
+// isXMLName reports whether name can be used as an element name without a
+// namespace prefix: a letter or underscore followed by letters, digits,
+// dots, hyphens and underscores.
+func isXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (r == '.' || r == '-' || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)):
+		default:
+			return false
+		}
+	}
+	return true
+}